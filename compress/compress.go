@@ -0,0 +1,45 @@
+// Package compress provides convenience functions that combine polyline
+// encoding with gzip compression.
+//
+// Compressing a single short polyline is rarely worthwhile, but large blobs of
+// concatenated polylines compress well.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+
+	"github.com/twpayne/go-polyline"
+)
+
+// EncodeCoordsGzip returns the gzip-compressed encoding of an array of
+// coordinates using the default codec.
+func EncodeCoordsGzip(coords [][]float64) ([]byte, error) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(polyline.EncodeCoords(coords)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// DecodeCoordsGzip decompresses buf and decodes the resulting array of
+// coordinates using the default codec. It returns the coordinates and any
+// error.
+func DecodeCoordsGzip(buf []byte) ([][]float64, error) {
+	r, err := gzip.NewReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	coords, _, err := polyline.DecodeCoords(data)
+	return coords, err
+}
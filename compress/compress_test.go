@@ -0,0 +1,28 @@
+package compress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzip(t *testing.T) {
+	for _, tc := range []struct {
+		cs [][]float64
+	}{
+		{
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+	} {
+		buf, err := EncodeCoordsGzip(tc.cs)
+		assert.NoError(t, err)
+		got, err := DecodeCoordsGzip(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.cs, got)
+	}
+}
+
+func TestDecodeCoordsGzipErrors(t *testing.T) {
+	_, err := DecodeCoordsGzip([]byte("_p~iF~ps|U_ulLnnqC_mqNvxq`@"))
+	assert.Error(t, err)
+}
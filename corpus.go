@@ -0,0 +1,59 @@
+package polyline
+
+import "fmt"
+
+// A CorpusCase is a pair of coordinates and their expected encoding, for use
+// with VerifyCorpus.
+type CorpusCase struct {
+	Coords  [][]float64
+	Encoded string
+}
+
+// VerifyCorpus checks that c encodes the coordinates of each case to its
+// expected encoding, and that c decodes each expected encoding to coordinates
+// equal to the case's coordinates at c's precision. It returns one error per
+// case, which is nil if the case passes both checks. If c is invalid then every
+// case's error is the reason why.
+func VerifyCorpus(cases []CorpusCase, c Codec) []error {
+	errs := make([]error, len(cases))
+	if err := c.validate(); err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	for i, cc := range cases {
+		if got := string(c.EncodeCoords(nil, cc.Coords)); got != cc.Encoded {
+			errs[i] = fmt.Errorf("polyline: case %d: %w: encoded %q, want %q", i, errCorpusMismatch, got, cc.Encoded)
+			continue
+		}
+		coords, _, err := c.DecodeCoords([]byte(cc.Encoded))
+		if err != nil {
+			errs[i] = fmt.Errorf("polyline: case %d: decode %q: %w", i, cc.Encoded, err)
+			continue
+		}
+		if !c.coordsEqual(coords, cc.Coords) {
+			errs[i] = fmt.Errorf("polyline: case %d: %w: decoded %v, want %v", i, errCorpusMismatch, coords, cc.Coords)
+		}
+	}
+	return errs
+}
+
+// coordsEqual returns whether a and b contain the same coordinates at c's
+// precision.
+func (c Codec) coordsEqual(a, b [][]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if c.toInt(j, a[i][j]) != c.toInt(j, b[i][j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
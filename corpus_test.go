@@ -0,0 +1,36 @@
+package polyline
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCorpus(t *testing.T) {
+	errs := VerifyCorpus([]CorpusCase{
+		{
+			Coords:  [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			Encoded: "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			Coords:  [][]float64{{38.500001, -120.2}},
+			Encoded: "_p~iF~ps|U",
+		},
+		{
+			Coords:  [][]float64{{38.5, -120.2}},
+			Encoded: "_p~iF~ps|U_ulLnnqC",
+		},
+		{
+			Coords:  nil,
+			Encoded: "",
+		},
+	}, defaultCodec)
+	assert.Len(t, errs, 4)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.EqualError(t, errs[2], `polyline: case 2: corpus mismatch: encoded "_p~iF~ps|U", want "_p~iF~ps|U_ulLnnqC"`)
+	assert.True(t, errors.Is(errs[2], errCorpusMismatch))
+	assert.EqualError(t, errs[3], `polyline: case 3: decode "": unterminated sequence`)
+	assert.True(t, errors.Is(errs[3], errUnterminatedSequence))
+}
@@ -0,0 +1,82 @@
+package polyline
+
+// A DiffOpType is the type of a DiffOp.
+type DiffOpType int
+
+// DiffOpTypes.
+const (
+	DiffMatch DiffOpType = iota
+	DiffDelete
+	DiffInsert
+)
+
+// A DiffOp is a single operation in an edit script that transforms one array
+// of coordinates into another. A is the index of the coordinate in the first
+// array for DiffMatch and DiffDelete, and B is the index of the coordinate in
+// the second array for DiffMatch and DiffInsert. Unused indexes are -1.
+type DiffOp struct {
+	Type DiffOpType
+	A    int
+	B    int
+}
+
+// Diff decodes the polylines a and b and returns a minimal edit script of
+// matches, deletions, and insertions that transforms the coordinates of a into
+// those of b, and any error. Coordinates are compared in scaled integer units,
+// so coordinates that are equal at c's precision match.
+func (c Codec) Diff(a, b []byte) ([]DiffOp, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	aInts, err := c.DecodeFlatInts(a)
+	if err != nil {
+		return nil, err
+	}
+	bInts, err := c.DecodeFlatInts(b)
+	if err != nil {
+		return nil, err
+	}
+	n, m := len(aInts)/c.Dim, len(bInts)/c.Dim
+	equal := func(i, j int) bool {
+		for k := 0; k < c.Dim; k++ {
+			if aInts[i*c.Dim+k] != bInts[j*c.Dim+k] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// lcs[i*(m+1)+j] is the length of the longest common subsequence of the
+	// coordinates a[i:] and b[j:].
+	lcs := make([]int, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case equal(i, j):
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j]
+			default:
+				lcs[i*(m+1)+j] = lcs[i*(m+1)+j+1]
+			}
+		}
+	}
+
+	ops := make([]DiffOp, 0, n+m-lcs[0])
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && equal(i, j):
+			ops = append(ops, DiffOp{Type: DiffMatch, A: i, B: j})
+			i++
+			j++
+		case j == m || i < n && lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+			ops = append(ops, DiffOp{Type: DiffDelete, A: i, B: -1})
+			i++
+		default:
+			ops = append(ops, DiffOp{Type: DiffInsert, A: -1, B: j})
+			j++
+		}
+	}
+	return ops, nil
+}
@@ -0,0 +1,86 @@
+package polyline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	p0 := []float64{38.5, -120.2}
+	p1 := []float64{40.7, -120.95}
+	p2 := []float64{43.252, -126.453}
+	p3 := []float64{44, -127}
+	for _, tc := range []struct {
+		a, b [][]float64
+		want []DiffOp
+	}{
+		{
+			a: [][]float64{p0, p1, p2},
+			b: [][]float64{p0, p1, p2},
+			want: []DiffOp{
+				{Type: DiffMatch, A: 0, B: 0},
+				{Type: DiffMatch, A: 1, B: 1},
+				{Type: DiffMatch, A: 2, B: 2},
+			},
+		},
+		{
+			a: [][]float64{p0, p1, p2},
+			b: [][]float64{p0, p2},
+			want: []DiffOp{
+				{Type: DiffMatch, A: 0, B: 0},
+				{Type: DiffDelete, A: 1, B: -1},
+				{Type: DiffMatch, A: 2, B: 1},
+			},
+		},
+		{
+			a: [][]float64{p0, p2},
+			b: [][]float64{p0, p1, p2, p3},
+			want: []DiffOp{
+				{Type: DiffMatch, A: 0, B: 0},
+				{Type: DiffInsert, A: -1, B: 1},
+				{Type: DiffMatch, A: 1, B: 2},
+				{Type: DiffInsert, A: -1, B: 3},
+			},
+		},
+		{
+			a: [][]float64{p0, p1},
+			b: [][]float64{p2, p3},
+			want: []DiffOp{
+				{Type: DiffDelete, A: 0, B: -1},
+				{Type: DiffDelete, A: 1, B: -1},
+				{Type: DiffInsert, A: -1, B: 0},
+				{Type: DiffInsert, A: -1, B: 1},
+			},
+		},
+		{
+			a: [][]float64{p0, {38.500001, -120.2}},
+			b: [][]float64{p0, p0},
+			want: []DiffOp{
+				{Type: DiffMatch, A: 0, B: 0},
+				{Type: DiffMatch, A: 1, B: 1},
+			},
+		},
+		{
+			a:    nil,
+			b:    [][]float64{p0},
+			want: []DiffOp{{Type: DiffInsert, A: -1, B: 0}},
+		},
+		{
+			a:    nil,
+			b:    nil,
+			want: []DiffOp{},
+		},
+	} {
+		got, err := defaultCodec.Diff(EncodeCoords(tc.a), EncodeCoords(tc.b))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestDiffErrors(t *testing.T) {
+	_, err := defaultCodec.Diff([]byte("_p~iF"), []byte("_p~iF~ps|U"))
+	assert.Equal(t, errUnterminatedSequence, err)
+	_, err = defaultCodec.Diff([]byte("_p~iF~ps|U"), []byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
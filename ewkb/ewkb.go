@@ -0,0 +1,39 @@
+// Package ewkb converts polylines to PostGIS Extended Well-Known Binary (EWKB).
+package ewkb
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+
+	"github.com/twpayne/go-polyline"
+)
+
+const (
+	wkbNDR        = 1
+	wkbLineString = 2
+	ewkbSRID      = 0x20000000
+)
+
+// DecodeToEWKBHex decodes an array of coordinates from buf using the default
+// codec and returns the hex encoding of a little-endian EWKB LineString with
+// the given SRID, with each coordinate's longitude as x and latitude as y, and
+// any error.
+func DecodeToEWKBHex(buf []byte, srid int) (string, error) {
+	coords, _, err := polyline.DecodeCoords(buf)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, 13, 13+16*len(coords))
+	b[0] = wkbNDR
+	binary.LittleEndian.PutUint32(b[1:5], wkbLineString|ewkbSRID)
+	binary.LittleEndian.PutUint32(b[5:9], uint32(srid))
+	binary.LittleEndian.PutUint32(b[9:13], uint32(len(coords)))
+	var point [16]byte
+	for _, coord := range coords {
+		binary.LittleEndian.PutUint64(point[0:8], math.Float64bits(coord[1]))
+		binary.LittleEndian.PutUint64(point[8:16], math.Float64bits(coord[0]))
+		b = append(b, point[:]...)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,35 @@
+package ewkb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeToEWKBHex(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		srid int
+		want string
+	}{
+		{
+			s:    "_p~iF~ps|U_ulLnnqC",
+			srid: 4326,
+			want: "0102000020e610000002000000cdcccccccc0c5ec00000000000404340cdcccccccc3c5ec09a99999999594440",
+		},
+		{
+			s:    "_ibE_seK",
+			srid: 3857,
+			want: "0102000020110f0000010000000000000000000040000000000000f03f",
+		},
+	} {
+		got, err := DecodeToEWKBHex([]byte(tc.s), tc.srid)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestDecodeToEWKBHexErrors(t *testing.T) {
+	_, err := DecodeToEWKBHex([]byte("_p~iF"), 4326)
+	assert.Error(t, err)
+}
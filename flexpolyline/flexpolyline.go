@@ -0,0 +1,149 @@
+// Package flexpolyline implements HERE's Flexible Polyline encoder and decoder.
+// See https://github.com/heremaps/flexible-polyline.
+//
+// Flexible Polyline uses the same variable-length integer encoding as Google's
+// Encoded Polyline, but with a different alphabet and a header that records the
+// precision and an optional third dimension.
+package flexpolyline
+
+import (
+	"errors"
+	"math"
+
+	"github.com/twpayne/go-polyline"
+)
+
+// A ThirdDimType is the type of the optional third dimension.
+type ThirdDimType int
+
+// Third dimension types.
+const (
+	Absent    ThirdDimType = 0
+	Level     ThirdDimType = 1
+	Altitude  ThirdDimType = 2
+	Elevation ThirdDimType = 3
+	Reserved1 ThirdDimType = 4
+	Reserved2 ThirdDimType = 5
+	Custom1   ThirdDimType = 6
+	Custom2   ThirdDimType = 7
+)
+
+const formatVersion = 1
+
+const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+var (
+	errDimensionalMismatch = errors.New("dimensional mismatch")
+	errInvalidHeader       = errors.New("invalid header")
+	errInvalidPrecision    = errors.New("invalid precision")
+	errInvalidThirdDimType = errors.New("invalid third dimension type")
+	errInvalidVersion      = errors.New("invalid version")
+)
+
+// fromPolyline and toPolyline translate between bytes in the Google Encoded
+// Polyline alphabet and bytes in the Flexible Polyline alphabet. Both encode
+// five bits per byte plus a continuation flag.
+var fromPolyline, toPolyline [256]byte
+
+func init() {
+	for i := 0; i < 32; i++ {
+		fromPolyline[63+i] = alphabet[i]
+		fromPolyline[95+i] = alphabet[32+i]
+		toPolyline[alphabet[i]] = byte(63 + i)
+		toPolyline[alphabet[32+i]] = byte(95 + i)
+	}
+}
+
+// translate replaces each byte in buf with its entry in table.
+func translate(buf []byte, table *[256]byte) {
+	for i, b := range buf {
+		buf[i] = table[b]
+	}
+}
+
+// Encode returns the Flexible Polyline encoding of coords. precision is the
+// number of decimal digits of latitude and longitude and thirdDimPrecision is
+// the number of decimal digits of the third dimension, both between 0 and 15.
+// If thirdDimType is Absent then each coordinate must have two dimensions,
+// otherwise it must have three.
+func Encode(coords [][]float64, precision, thirdDimPrecision int, thirdDimType ThirdDimType) (string, error) {
+	if precision < 0 || precision > 15 || thirdDimPrecision < 0 || thirdDimPrecision > 15 {
+		return "", errInvalidPrecision
+	}
+	if thirdDimType < Absent || thirdDimType > Custom2 {
+		return "", errInvalidThirdDimType
+	}
+	dim := 3
+	if thirdDimType == Absent {
+		dim = 2
+	}
+	scales := []float64{math.Pow10(precision), math.Pow10(precision), math.Pow10(thirdDimPrecision)}
+	buf := polyline.EncodeUint(nil, formatVersion)
+	buf = polyline.EncodeUint(buf, uint(thirdDimPrecision<<7|int(thirdDimType)<<4|precision))
+	last := make([]int, dim)
+	for _, coord := range coords {
+		if len(coord) != dim {
+			return "", errDimensionalMismatch
+		}
+		for i, x := range coord {
+			ex := round(scales[i] * x)
+			buf = polyline.EncodeInt(buf, ex-last[i])
+			last[i] = ex
+		}
+	}
+	translate(buf, &fromPolyline)
+	return string(buf), nil
+}
+
+// Decode decodes the Flexible Polyline s. It returns the coordinates, the
+// precision, the third dimension precision, the third dimension type, and any
+// error.
+func Decode(s string) ([][]float64, int, int, ThirdDimType, error) {
+	buf := []byte(s)
+	translate(buf, &toPolyline)
+	version, buf, err := polyline.DecodeUint(buf)
+	if err != nil {
+		return nil, 0, 0, Absent, err
+	}
+	if version != formatVersion {
+		return nil, 0, 0, Absent, errInvalidVersion
+	}
+	header, buf, err := polyline.DecodeUint(buf)
+	if err != nil {
+		return nil, 0, 0, Absent, err
+	}
+	if header >= 1<<11 {
+		return nil, 0, 0, Absent, errInvalidHeader
+	}
+	precision := int(header & 15)
+	thirdDimType := ThirdDimType(header >> 4 & 7)
+	thirdDimPrecision := int(header >> 7)
+	dim := 3
+	if thirdDimType == Absent {
+		dim = 2
+	}
+	scales := []float64{math.Pow10(precision), math.Pow10(precision), math.Pow10(thirdDimPrecision)}
+	var coords [][]float64
+	last := make([]int, dim)
+	for len(buf) > 0 {
+		coord := make([]float64, dim)
+		for i := range coord {
+			var k int
+			k, buf, err = polyline.DecodeInt(buf)
+			if err != nil {
+				return nil, 0, 0, Absent, err
+			}
+			last[i] += k
+			coord[i] = float64(last[i]) / scales[i]
+		}
+		coords = append(coords, coord)
+	}
+	return coords, precision, thirdDimPrecision, thirdDimType, nil
+}
+
+func round(x float64) int {
+	if x < 0 {
+		return int(-math.Floor(-x + 0.5))
+	}
+	return int(math.Floor(x + 0.5))
+}
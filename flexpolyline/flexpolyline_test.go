@@ -0,0 +1,112 @@
+package flexpolyline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlexPolyline(t *testing.T) {
+	for _, tc := range []struct {
+		cs                [][]float64
+		precision         int
+		thirdDimPrecision int
+		thirdDimType      ThirdDimType
+		s                 string
+		decoded           [][]float64
+	}{
+		{
+			cs: [][]float64{
+				{50.1022829, 8.6982122},
+				{50.1020076, 8.6956695},
+				{50.1006313, 8.6914960},
+				{50.0987800, 8.6875156},
+			},
+			precision: 5,
+			s:         "BFoz5xJ67i1B1B7PzIhaxL7Y",
+			decoded: [][]float64{
+				{50.10228, 8.69821},
+				{50.10201, 8.69567},
+				{50.10063, 8.69150},
+				{50.09878, 8.68752},
+			},
+		},
+		{
+			cs: [][]float64{
+				{50.10228, 8.69821, 10},
+				{50.10201, 8.69567, 20},
+				{50.10063, 8.69150, 30},
+				{50.09878, 8.68752, 40},
+			},
+			precision:    5,
+			thirdDimType: Altitude,
+			s:            "BlBoz5xJ67i1BU1B7PUzIhaUxL7YU",
+		},
+		{
+			cs: [][]float64{
+				{-38.5, 120.2, 0.25},
+				{-40.7, 120.95, -1.5},
+			},
+			precision:         7,
+			thirdDimPrecision: 2,
+			thirdDimType:      Custom2,
+			s:                 "",
+		},
+		{
+			cs:        nil,
+			precision: 6,
+			s:         "BG",
+		},
+	} {
+		got, err := Encode(tc.cs, tc.precision, tc.thirdDimPrecision, tc.thirdDimType)
+		assert.NoError(t, err)
+		if tc.s != "" {
+			assert.Equal(t, tc.s, got)
+		}
+		gotCoords, gotPrecision, gotThirdDimPrecision, gotThirdDimType, err := Decode(got)
+		assert.NoError(t, err)
+		if tc.decoded != nil {
+			assert.Equal(t, tc.decoded, gotCoords)
+		} else {
+			assert.Equal(t, tc.cs, gotCoords)
+		}
+		assert.Equal(t, tc.precision, gotPrecision)
+		assert.Equal(t, tc.thirdDimPrecision, gotThirdDimPrecision)
+		assert.Equal(t, tc.thirdDimType, gotThirdDimType)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		cs                [][]float64
+		precision         int
+		thirdDimPrecision int
+		thirdDimType      ThirdDimType
+		err               error
+	}{
+		{precision: -1, err: errInvalidPrecision},
+		{precision: 16, err: errInvalidPrecision},
+		{thirdDimPrecision: 16, err: errInvalidPrecision},
+		{thirdDimType: 8, err: errInvalidThirdDimType},
+		{cs: [][]float64{{1, 2, 3}}, err: errDimensionalMismatch},
+		{cs: [][]float64{{1, 2}}, thirdDimType: Level, err: errDimensionalMismatch},
+	} {
+		_, err := Encode(tc.cs, tc.precision, tc.thirdDimPrecision, tc.thirdDimType)
+		assert.Equal(t, tc.err, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"B",
+		"CF",
+		"B___B",
+		"BFoz5xJ67i1B1B7PzIhaxL7",
+		"BFoz5xJ67i1B1B7PzIhaxL",
+		"BFoz5xJ67i1B1B7PzIhaxL7Y!",
+	} {
+		_, _, _, _, err := Decode(s)
+		assert.Error(t, err, s)
+	}
+}
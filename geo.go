@@ -0,0 +1,1048 @@
+package polyline
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// earthRadius is the mean radius of the Earth in meters.
+const earthRadius = 6371008.8
+
+// WGS84 ellipsoid parameters.
+const (
+	wgs84A = 6378137
+	wgs84F = 1 / 298.257223563
+	wgs84B = (1 - wgs84F) * wgs84A
+)
+
+// radians converts x from degrees to radians.
+func radians(x float64) float64 {
+	return x * math.Pi / 180
+}
+
+// degrees converts x from radians to degrees.
+func degrees(x float64) float64 {
+	return x * 180 / math.Pi
+}
+
+// haversine returns the great circle distance in meters between p0 and p1 on
+// a spherical Earth.
+func haversine(p0, p1 []float64) float64 {
+	phi0, phi1 := radians(p0[0]), radians(p1[0])
+	dPhi, dLambda := phi1-phi0, radians(p1[1]-p0[1])
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi0)*math.Cos(phi1)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// vincenty returns the distance in meters between p0 and p1 on the WGS84
+// ellipsoid using Vincenty's inverse formula, and whether the formula
+// converged.
+func vincenty(p0, p1 []float64) (float64, bool) {
+	l := radians(p1[1] - p0[1])
+	u1 := math.Atan((1 - wgs84F) * math.Tan(radians(p0[0])))
+	u2 := math.Atan((1 - wgs84F) * math.Tan(radians(p1[0])))
+	sinU1, cosU1 := math.Sincos(u1)
+	sinU2, cosU2 := math.Sincos(u2)
+	lambda := l
+	for i := 0; i < 200; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma := math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0, true
+		}
+		cosSigma := sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma := math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha := 1 - sinAlpha*sinAlpha
+		cos2SigmaM := 0.0
+		if cosSqAlpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+		c := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+		lambdaPrev := lambda
+		lambda = l + (1-c)*wgs84F*sinAlpha*(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-lambdaPrev) < 1e-12 {
+			uSq := cosSqAlpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+			a := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+			b := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+			deltaSigma := b * sinSigma * (cos2SigmaM + b/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-b/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+			return wgs84B * a * (sigma - deltaSigma), true
+		}
+	}
+	return 0, false
+}
+
+// bearing returns the initial bearing in degrees clockwise from north of the
+// great circle from p0 to p1, in the range [0, 360).
+func bearing(p0, p1 []float64) float64 {
+	phi0, phi1 := radians(p0[0]), radians(p1[0])
+	dLambda := radians(p1[1] - p0[1])
+	y := math.Sin(dLambda) * math.Cos(phi1)
+	x := math.Cos(phi0)*math.Sin(phi1) - math.Sin(phi0)*math.Cos(phi1)*math.Cos(dLambda)
+	return math.Mod(degrees(math.Atan2(y, x))+360, 360)
+}
+
+// cross returns the z component of the cross product of the vectors OA and OB,
+// treating coordinates as (lat, lng) and using lng as x and lat as y.
+func cross(o, a, b []float64) float64 {
+	return (a[1]-o[1])*(b[0]-o[0]) - (a[0]-o[0])*(b[1]-o[1])
+}
+
+// DecodeConvexHull decodes an array of coordinates from buf using the default
+// codec and returns their convex hull as a closed ring in counter-clockwise
+// order, treating longitude as x and latitude as y. If there are fewer than
+// three unique coordinates then it returns the decoded coordinates unchanged.
+func DecodeConvexHull(buf []byte) ([][]float64, error) {
+	coords, _, err := DecodeCoords(buf)
+	if err != nil {
+		return nil, err
+	}
+	points := make([][]float64, len(coords))
+	copy(points, coords)
+	sort.Slice(points, func(i, j int) bool {
+		if points[i][1] != points[j][1] {
+			return points[i][1] < points[j][1]
+		}
+		return points[i][0] < points[j][0]
+	})
+	unique := points[:1]
+	for _, p := range points[1:] {
+		if last := unique[len(unique)-1]; p[0] != last[0] || p[1] != last[1] {
+			unique = append(unique, p)
+		}
+	}
+	if len(unique) < 3 {
+		return coords, nil
+	}
+	hull := make([][]float64, 0, 2*len(unique))
+	for _, p := range unique {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	for i, lower := len(unique)-2, len(hull)+1; i >= 0; i-- {
+		p := unique[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull, nil
+}
+
+// inBounds returns whether coord lies within the given bounds, inclusive.
+func inBounds(coord []float64, minLat, minLng, maxLat, maxLng float64) bool {
+	return minLat <= coord[0] && coord[0] <= maxLat && minLng <= coord[1] && coord[1] <= maxLng
+}
+
+// EncodeBBox appends the encoding of the closed rectangle with the given
+// bounds to buf and returns the new buf. The rectangle starts and ends at the
+// south-west corner and visits the south-east, north-east, and north-west
+// corners in order. c.Dim must be 2, otherwise EncodeBBox panics.
+func (c Codec) EncodeBBox(buf []byte, minLat, minLng, maxLat, maxLng float64) []byte {
+	c.mustValidate()
+	if c.Dim != 2 {
+		panic("polyline: dimensional mismatch")
+	}
+	return c.EncodeCoords(buf, [][]float64{
+		{minLat, minLng},
+		{minLat, maxLng},
+		{maxLat, maxLng},
+		{maxLat, minLng},
+		{minLat, minLng},
+	})
+}
+
+// ClipToBounds decodes buf and returns the encoding of the coordinates from the
+// first to the last coordinate that lie within the given bounds, inclusive.
+// Coordinates between these are kept even if they lie outside the bounds. If
+// no coordinates lie within the bounds then it returns nil. c.Dim must be 2.
+func (c Codec) ClipToBounds(buf []byte, minLat, minLng, maxLat, maxLng float64) ([]byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if c.Dim != 2 {
+		return nil, errDimensionalMismatch
+	}
+	coords, _, err := c.DecodeCoords(buf)
+	if err != nil {
+		return nil, err
+	}
+	first, last := -1, -1
+	for i, coord := range coords {
+		if inBounds(coord, minLat, minLng, maxLat, maxLng) {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first == -1 {
+		return nil, nil
+	}
+	return c.EncodeCoords(nil, coords[first:last+1]), nil
+}
+
+// clipSegment clips the segment from p0 to p1 to the given bounds using the
+// Liang-Barsky algorithm. It returns the parameters of the start and end of
+// the clipped segment and whether any part of the segment lies within the
+// bounds.
+func clipSegment(p0, p1 []float64, minLat, minLng, maxLat, maxLng float64) (float64, float64, bool) {
+	dLat, dLng := p1[0]-p0[0], p1[1]-p0[1]
+	t0, t1 := 0.0, 1.0
+	for _, pq := range [4][2]float64{
+		{-dLat, p0[0] - minLat},
+		{dLat, maxLat - p0[0]},
+		{-dLng, p0[1] - minLng},
+		{dLng, maxLng - p0[1]},
+	} {
+		p, q := pq[0], pq[1]
+		switch {
+		case p == 0:
+			if q < 0 {
+				return 0, 0, false
+			}
+		case p < 0:
+			if r := q / p; r > t1 {
+				return 0, 0, false
+			} else if r > t0 {
+				t0 = r
+			}
+		default:
+			if r := q / p; r < t0 {
+				return 0, 0, false
+			} else if r < t1 {
+				t1 = r
+			}
+		}
+	}
+	return t0, t1, true
+}
+
+// interpolate returns the point a fraction t of the way from p0 to p1.
+func interpolate(p0, p1 []float64, t float64) []float64 {
+	switch t {
+	case 0:
+		return append([]float64(nil), p0...)
+	case 1:
+		return append([]float64(nil), p1...)
+	}
+	p := make([]float64, len(p0))
+	for i := range p {
+		p[i] = p0[i] + t*(p1[i]-p0[i])
+	}
+	return p
+}
+
+// DecodeClip decodes an array of coordinates from buf using the default codec
+// and clips the resulting polyline to the given bounds, inserting points where
+// the polyline crosses the boundary. It returns the parts of the polyline that
+// lie within the bounds, in order, and any error. A polyline that leaves and
+// re-enters the bounds results in multiple parts.
+func DecodeClip(buf []byte, minLat, minLng, maxLat, maxLng float64) ([][][]float64, error) {
+	coords, _, err := DecodeCoords(buf)
+	if err != nil {
+		return nil, err
+	}
+	var parts [][][]float64
+	if len(coords) == 1 {
+		if inBounds(coords[0], minLat, minLng, maxLat, maxLng) {
+			parts = append(parts, coords)
+		}
+		return parts, nil
+	}
+	var part [][]float64
+	for i := 1; i < len(coords); i++ {
+		p0, p1 := coords[i-1], coords[i]
+		t0, t1, ok := clipSegment(p0, p1, minLat, minLng, maxLat, maxLng)
+		if !ok {
+			parts = appendPart(parts, part)
+			part = nil
+			continue
+		}
+		if part == nil || t0 > 0 {
+			parts = appendPart(parts, part)
+			part = [][]float64{interpolate(p0, p1, t0)}
+		}
+		// A zero-length span only touches the bounds, so its exit point
+		// duplicates the last point of the part.
+		if t0 < t1 {
+			part = append(part, interpolate(p0, p1, t1))
+		}
+		if t1 < 1 {
+			parts = appendPart(parts, part)
+			part = nil
+		}
+	}
+	return appendPart(parts, part), nil
+}
+
+// appendPart appends part to parts unless it is a single point where the
+// polyline only touches the bounds.
+func appendPart(parts [][][]float64, part [][]float64) [][][]float64 {
+	if len(part) < 2 {
+		return parts
+	}
+	return append(parts, part)
+}
+
+// DecodeBearings decodes an array of coordinates from buf using the default
+// codec and returns the initial bearing in degrees clockwise from north from
+// each coordinate to the next, and any error. There is one bearing for each
+// coordinate: the last coordinate repeats the bearing of the previous one. If
+// there is only one coordinate then its bearing is NaN.
+func DecodeBearings(buf []byte) ([]float64, error) {
+	coords, _, err := DecodeCoords(buf)
+	if err != nil {
+		return nil, err
+	}
+	bearings := make([]float64, len(coords))
+	bearings[0] = math.NaN()
+	for i := 1; i < len(coords); i++ {
+		bearings[i-1] = bearing(coords[i-1], coords[i])
+	}
+	if n := len(bearings); n > 1 {
+		bearings[n-1] = bearings[n-2]
+	}
+	return bearings, nil
+}
+
+// DecodeTurnCount decodes an array of coordinates from buf using the default
+// codec and returns the number of vertices at which the initial bearing of the
+// next segment differs from that of the previous segment by more than
+// minTurnDegrees, and any error. Segments between equal coordinates have no
+// bearing and are skipped.
+func DecodeTurnCount(buf []byte, minTurnDegrees float64) (int, error) {
+	coords, _, err := DecodeCoords(buf)
+	if err != nil {
+		return 0, err
+	}
+	turns := 0
+	prevBearing := math.NaN()
+	for i := 1; i < len(coords); i++ {
+		if coords[i][0] == coords[i-1][0] && coords[i][1] == coords[i-1][1] {
+			continue
+		}
+		b := bearing(coords[i-1], coords[i])
+		if !math.IsNaN(prevBearing) && math.Abs(math.Mod(b-prevBearing+540, 360)-180) > minTurnDegrees {
+			turns++
+		}
+		prevBearing = b
+	}
+	return turns, nil
+}
+
+// DecodeTotalTurning decodes an array of coordinates from buf using the
+// default codec and returns the sum of the absolute changes in initial bearing
+// at each interior vertex, in radians, and any error. Segments between equal
+// coordinates have no bearing and are skipped. Polylines with fewer than three
+// coordinates have a total turning of zero.
+func DecodeTotalTurning(buf []byte) (float64, error) {
+	coords, _, err := DecodeCoords(buf)
+	if err != nil {
+		return 0, err
+	}
+	total := 0.0
+	prevBearing := math.NaN()
+	for i := 1; i < len(coords); i++ {
+		if coords[i][0] == coords[i-1][0] && coords[i][1] == coords[i-1][1] {
+			continue
+		}
+		b := bearing(coords[i-1], coords[i])
+		if !math.IsNaN(prevBearing) {
+			total += math.Abs(math.Mod(b-prevBearing+540, 360) - 180)
+		}
+		prevBearing = b
+	}
+	return radians(total), nil
+}
+
+// DecodeMeanBearing decodes an array of coordinates from buf using the default
+// codec and returns the circular mean of the initial bearings of its segments,
+// weighted by their lengths, in degrees clockwise from north, and any error.
+// It returns errTooFewCoords if buf contains fewer than two distinct
+// coordinates.
+func DecodeMeanBearing(buf []byte) (float64, error) {
+	coords, _, err := DecodeCoords(buf)
+	if err != nil {
+		return 0, err
+	}
+	var x, y, length float64
+	for i := 1; i < len(coords); i++ {
+		d := haversine(coords[i-1], coords[i])
+		if d == 0 {
+			continue
+		}
+		sin, cos := math.Sincos(radians(bearing(coords[i-1], coords[i])))
+		x += d * cos
+		y += d * sin
+		length += d
+	}
+	if length == 0 {
+		return 0, errTooFewCoords
+	}
+	return math.Mod(degrees(math.Atan2(y, x))+360, 360), nil
+}
+
+// distance returns the distance between p0 and p1. For Geographic coordinates
+// with at least two dimensions it is the great circle distance in meters
+// between the latitudes and longitudes, ignoring any further dimensions such as
+// altitude, otherwise it is the Euclidean distance in coordinate units.
+func (c Codec) distance(p0, p1 []float64) float64 {
+	if c.CoordSystem == Geographic && c.Dim >= 2 {
+		if c.Radians {
+			return haversine([]float64{degrees(p0[0]), degrees(p0[1])}, []float64{degrees(p1[0]), degrees(p1[1])})
+		}
+		return haversine(p0, p1)
+	}
+	sum := 0.0
+	for i := range p0 {
+		d := p1[i] - p0[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+// DecodeLength decodes an array of coordinates from buf and returns the length
+// of the resulting polyline, and any error. For Geographic coordinates with at
+// least two dimensions the length is the sum of the great circle distances in
+// meters between the latitudes and longitudes, ignoring any further dimensions
+// such as altitude. Otherwise, including for Projected coordinates, it is the
+// sum of the Euclidean distances in coordinate units.
+func (c Codec) DecodeLength(buf []byte) (float64, error) {
+	if err := c.validate(); err != nil {
+		return 0, err
+	}
+	coords, _, err := c.DecodeCoords(buf)
+	if err != nil {
+		return 0, err
+	}
+	length := 0.0
+	for i := 1; i < len(coords); i++ {
+		length += c.distance(coords[i-1], coords[i])
+	}
+	return length, nil
+}
+
+// DecodeNearestVertex decodes an array of coordinates from buf and returns the
+// index of the coordinate closest to coord, its distance from coord, and any
+// error. Distances are measured as in DecodeLength. The coordinates are not
+// stored, so memory use is independent of the length of buf.
+func (c Codec) DecodeNearestVertex(buf []byte, coord []float64) (index int, dist float64, err error) {
+	if err := c.validate(); err != nil {
+		return -1, 0, err
+	}
+	if len(coord) != c.Dim {
+		return -1, 0, errDimensionalMismatch
+	}
+	index, dist = -1, math.Inf(1)
+	last := make([]int, c.Dim)
+	p := make([]float64, c.Dim)
+	for i := 0; i == 0 || len(buf) > 0; i++ {
+		if buf, err = c.decodeCoord(buf, last, p); err != nil {
+			return -1, 0, err
+		}
+		if d := c.distance(coord, p); d < dist {
+			index, dist = i, d
+		}
+	}
+	return index, dist, nil
+}
+
+// DecodeLengthVincenty decodes an array of coordinates from buf using the
+// default codec and returns the length of the resulting polyline in meters on
+// the WGS84 ellipsoid, computed with Vincenty's inverse formula, and any
+// error. Vincenty's formula does not converge for some nearly antipodal pairs
+// of points; for such segments the spherical haversine distance is used
+// instead, which may be in error by up to about 0.5%.
+func DecodeLengthVincenty(buf []byte) (float64, error) {
+	coords, _, err := DecodeCoords(buf)
+	if err != nil {
+		return 0, err
+	}
+	length := 0.0
+	for i := 1; i < len(coords); i++ {
+		d, ok := vincenty(coords[i-1], coords[i])
+		if !ok {
+			d = haversine(coords[i-1], coords[i])
+		}
+		length += d
+	}
+	return length, nil
+}
+
+// DecodeCoordsThin decodes an array of coordinates from buf using the default
+// codec and drops every coordinate that is less than minMeters from the
+// previous kept coordinate. The first and last coordinates are always kept. It
+// returns the kept coordinates and any error.
+func DecodeCoordsThin(buf []byte, minMeters float64) ([][]float64, error) {
+	coords, _, err := DecodeCoords(buf)
+	if err != nil {
+		return nil, err
+	}
+	thinned := coords[:1]
+	for i := 1; i < len(coords)-1; i++ {
+		if haversine(thinned[len(thinned)-1], coords[i]) >= minMeters {
+			thinned = append(thinned, coords[i])
+		}
+	}
+	if len(coords) > 1 {
+		thinned = append(thinned, coords[len(coords)-1])
+	}
+	return thinned, nil
+}
+
+// segmentDistance returns the planar distance from p to the segment ab,
+// treating coordinates as (lat, lng) and using lng as x and lat as y.
+func segmentDistance(p, a, b []float64) float64 {
+	dx, dy := b[1]-a[1], b[0]-a[0]
+	t := 0.0
+	if d2 := dx*dx + dy*dy; d2 != 0 {
+		t = ((p[1]-a[1])*dx + (p[0]-a[0])*dy) / d2
+		t = math.Max(0, math.Min(1, t))
+	}
+	return math.Hypot(p[1]-a[1]-t*dx, p[0]-a[0]-t*dy)
+}
+
+// crossTrackDistance returns the great circle distance in meters from p to the
+// segment ab on a spherical Earth.
+func crossTrackDistance(p, a, b []float64) float64 {
+	d13 := haversine(a, p) / earthRadius
+	dTheta := radians(bearing(a, p) - bearing(a, b))
+	if math.Cos(dTheta) < 0 {
+		return haversine(a, p)
+	}
+	dxt := math.Asin(math.Sin(d13) * math.Sin(dTheta))
+	dat := math.Acos(math.Max(-1, math.Min(1, math.Cos(d13)/math.Cos(dxt))))
+	if dat*earthRadius > haversine(a, b) {
+		return haversine(b, p)
+	}
+	return math.Abs(dxt) * earthRadius
+}
+
+// simplify returns coords simplified with the Ramer-Douglas-Peucker algorithm,
+// keeping every coordinate that is more than epsilon from the simplified line
+// as measured by distance. The first and last coordinates are always kept.
+func simplify(coords [][]float64, epsilon float64, distance func(p, a, b []float64) float64) [][]float64 {
+	if len(coords) < 3 {
+		return coords
+	}
+	keep := make([]bool, len(coords))
+	keep[0], keep[len(coords)-1] = true, true
+	stack := [][2]int{{0, len(coords) - 1}}
+	for len(stack) > 0 {
+		first, last := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+		index, maxDistance := 0, epsilon
+		for i := first + 1; i < last; i++ {
+			if d := distance(coords[i], coords[first], coords[last]); d > maxDistance {
+				index, maxDistance = i, d
+			}
+		}
+		if index != 0 {
+			keep[index] = true
+			stack = append(stack, [2]int{first, index}, [2]int{index, last})
+		}
+	}
+	var simplified [][]float64
+	for i, coord := range coords {
+		if keep[i] {
+			simplified = append(simplified, coord)
+		}
+	}
+	return simplified
+}
+
+// SimplifyToBudget simplifies coords with the Ramer-Douglas-Peucker algorithm,
+// searching for the smallest tolerance that leaves at most maxPoints
+// coordinates. Distances are planar, using lng as x and lat as y. The first and
+// last coordinates are always kept, so the result has at least two coordinates
+// if coords does. If coords already has at most maxPoints coordinates then it
+// is returned unchanged.
+func SimplifyToBudget(coords [][]float64, maxPoints int) [][]float64 {
+	if len(coords) <= maxPoints || len(coords) < 3 {
+		return coords
+	}
+	minLat, minLng := coords[0][0], coords[0][1]
+	maxLat, maxLng := minLat, minLng
+	for _, coord := range coords[1:] {
+		minLat, maxLat = math.Min(minLat, coord[0]), math.Max(maxLat, coord[0])
+		minLng, maxLng = math.Min(minLng, coord[1]), math.Max(maxLng, coord[1])
+	}
+	lo, hi := 0.0, math.Hypot(maxLat-minLat, maxLng-minLng)
+	best := simplify(coords, hi, segmentDistance)
+	for i := 0; i < 64 && lo < hi; i++ {
+		mid := (lo + hi) / 2
+		if simplified := simplify(coords, mid, segmentDistance); len(simplified) <= maxPoints {
+			best, hi = simplified, mid
+		} else {
+			lo = mid
+		}
+	}
+	return best
+}
+
+// ConcatSimplify decodes the polylines in bufs, concatenates their coordinates,
+// simplifies the result with the Ramer-Douglas-Peucker algorithm with
+// tolerance epsilon, and returns its encoding and any error. Empty polylines
+// are skipped, and a coordinate equal at c's precision to the one before it,
+// such as the shared endpoint of two stitched segments, is dropped. Distances
+// are planar in the first two dimensions, using the second as x and the first
+// as y. c.Dim must be at least 2.
+func (c Codec) ConcatSimplify(bufs [][]byte, epsilon float64) ([]byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if c.Dim < 2 {
+		return nil, errDimensionalMismatch
+	}
+	var coords [][]float64
+	for _, buf := range bufs {
+		if len(buf) == 0 {
+			continue
+		}
+		segment, _, err := c.DecodeCoords(buf)
+		if err != nil {
+			return nil, err
+		}
+		for _, coord := range segment {
+			if len(coords) == 0 || !c.coordsEqual(coords[len(coords)-1:], [][]float64{coord}) {
+				coords = append(coords, coord)
+			}
+		}
+	}
+	return c.EncodeCoords(nil, simplify(coords, epsilon, segmentDistance)), nil
+}
+
+// SimplifyGeo simplifies coords with the Ramer-Douglas-Peucker algorithm,
+// keeping every coordinate that is more than epsilonMeters from the simplified
+// line. Distances are great circle cross-track distances on a spherical Earth,
+// so, unlike planar simplification, the tolerance is the same at all latitudes.
+// The first and last coordinates are always kept.
+func SimplifyGeo(coords [][]float64, epsilonMeters float64) [][]float64 {
+	return simplify(coords, epsilonMeters, crossTrackDistance)
+}
+
+// DecodeGeodesicArea decodes an array of coordinates from buf using the default
+// codec, closing the ring if needed, and returns the area that it encloses on a
+// spherical Earth in square meters, and any error. The area is computed with
+// the method of Chamberlain and Duquette.
+func DecodeGeodesicArea(buf []byte) (float64, error) {
+	coords, err := DecodeRingCoords(buf)
+	if err != nil {
+		return 0, err
+	}
+	area := 0.0
+	for i := 1; i < len(coords); i++ {
+		p0, p1 := coords[i-1], coords[i]
+		area += radians(p1[1]-p0[1]) * (2 + math.Sin(radians(p0[0])) + math.Sin(radians(p1[0])))
+	}
+	return math.Abs(area) * earthRadius * earthRadius / 2, nil
+}
+
+// DecodeContains decodes an array of coordinates from buf using the default
+// codec, closing the ring if needed, and returns whether the point at lat and
+// lng lies inside it, and any error. Points exactly on an edge or vertex of
+// the ring are inside. Containment is tested by ray casting, treating longitude
+// as x and latitude as y, so rings that cross the antimeridian are not
+// supported.
+func DecodeContains(buf []byte, lat, lng float64) (bool, error) {
+	coords, err := DecodeRingCoords(buf)
+	if err != nil {
+		return false, err
+	}
+	p := []float64{lat, lng}
+	inside := false
+	for i := 1; i < len(coords); i++ {
+		a, b := coords[i-1], coords[i]
+		if cross(a, b, p) == 0 &&
+			math.Min(a[0], b[0]) <= lat && lat <= math.Max(a[0], b[0]) &&
+			math.Min(a[1], b[1]) <= lng && lng <= math.Max(a[1], b[1]) {
+			return true, nil
+		}
+		if (a[0] > lat) != (b[0] > lat) && lng < (b[1]-a[1])*(lat-a[0])/(b[0]-a[0])+a[1] {
+			inside = !inside
+		}
+	}
+	return inside, nil
+}
+
+// DecodeSinuosity decodes an array of coordinates from buf using the default
+// codec and returns the ratio of the great circle length of the route to the
+// great circle distance between its endpoints, and any error. A straight route
+// has a sinuosity of one. If the endpoints coincide then it returns +Inf.
+func DecodeSinuosity(buf []byte) (float64, error) {
+	coords, _, err := DecodeCoords(buf)
+	if err != nil {
+		return 0, err
+	}
+	distance := haversine(coords[0], coords[len(coords)-1])
+	if distance == 0 {
+		return math.Inf(1), nil
+	}
+	length := 0.0
+	for i := 1; i < len(coords); i++ {
+		length += haversine(coords[i-1], coords[i])
+	}
+	return length / distance, nil
+}
+
+// maxAccuracyPrecision is the greatest precision chosen by
+// EncodeCoordsAccuracy.
+const maxAccuracyPrecision = 10
+
+// EncodeCoordsAccuracy returns the encoding of an array of two-dimensional
+// coordinates using the smallest power-of-ten scale whose rounding error is at
+// most maxErrorMeters, the precision (the base ten logarithm of the scale),
+// and any error. The rounding error of a scale is half of one scaled unit, and
+// is converted to meters using the length of a degree at the equator, which
+// overestimates the error of longitudes away from the equator. It returns an
+// error if maxErrorMeters is not positive or needs a precision greater than
+// 10.
+func EncodeCoordsAccuracy(coords [][]float64, maxErrorMeters float64) ([]byte, int, error) {
+	if !(maxErrorMeters > 0) {
+		return nil, 0, errInvalidAccuracy
+	}
+	metersPerDegree := radians(earthRadius)
+	for precision := 0; precision <= maxAccuracyPrecision; precision++ {
+		scale := math.Pow10(precision)
+		if 0.5/scale*metersPerDegree <= maxErrorMeters {
+			return Codec{Dim: 2, Scale: scale}.EncodeCoords(nil, coords), precision, nil
+		}
+	}
+	return nil, 0, errInvalidAccuracy
+}
+
+// GuessScale heuristically guesses the scale with which buf was encoded, given
+// its known first coordinate lat and lng. It decodes the first coordinate of
+// buf with scales of 1e5 and 1e6 and returns the scale whose result is closest
+// to lat and lng, and any error. It cannot detect other scales, and the guess
+// is only as reliable as the known first coordinate.
+func GuessScale(buf []byte, lat, lng float64) (float64, error) {
+	x, buf, err := DecodeInt(buf)
+	if err != nil {
+		return 0, err
+	}
+	y, _, err := DecodeInt(buf)
+	if err != nil {
+		return 0, err
+	}
+	bestScale, bestDistance := 0.0, math.Inf(1)
+	for _, scale := range []float64{1e5, 1e6} {
+		if d := math.Hypot(float64(x)/scale-lat, float64(y)/scale-lng); d < bestDistance {
+			bestScale, bestDistance = scale, d
+		}
+	}
+	return bestScale, nil
+}
+
+// A circle is a planar circle, using lng as x and lat as y.
+type circle struct {
+	lat, lng, r float64
+}
+
+// contains returns whether p lies within c, allowing for rounding errors.
+func (c circle) contains(p []float64) bool {
+	return math.Hypot(p[0]-c.lat, p[1]-c.lng) <= c.r*(1+1e-12)+1e-12
+}
+
+// circleFrom2 returns the smallest circle enclosing a and b.
+func circleFrom2(a, b []float64) circle {
+	return circle{
+		lat: (a[0] + b[0]) / 2,
+		lng: (a[1] + b[1]) / 2,
+		r:   math.Hypot(a[0]-b[0], a[1]-b[1]) / 2,
+	}
+}
+
+// circleFrom3 returns the smallest circle enclosing a, b, and c, which lie on
+// its boundary unless they are collinear.
+func circleFrom3(a, b, c []float64) circle {
+	bx, by := b[1]-a[1], b[0]-a[0]
+	cx, cy := c[1]-a[1], c[0]-a[0]
+	d := 2 * (bx*cy - by*cx)
+	if d == 0 {
+		circles := []circle{circleFrom2(a, b), circleFrom2(a, c), circleFrom2(b, c)}
+		sort.Slice(circles, func(i, j int) bool { return circles[i].r > circles[j].r })
+		return circles[0]
+	}
+	b2, c2 := bx*bx+by*by, cx*cx+cy*cy
+	x := (cy*b2 - by*c2) / d
+	y := (bx*c2 - cx*b2) / d
+	return circle{lat: a[0] + y, lng: a[1] + x, r: math.Hypot(x, y)}
+}
+
+// DecodeEnclosingCircle decodes an array of coordinates from buf using the
+// default codec and returns the center of their smallest enclosing circle, its
+// radius in meters, and any error. The circle is found with Welzl's algorithm,
+// treating longitude as x and latitude as y, and the radius is the greatest
+// great circle distance from the center to any coordinate.
+func DecodeEnclosingCircle(buf []byte) (centerLat, centerLng, radiusMeters float64, err error) {
+	coords, _, err := DecodeCoords(buf)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	points := make([][]float64, len(coords))
+	copy(points, coords)
+	r := rand.New(rand.NewSource(1))
+	r.Shuffle(len(points), func(i, j int) { points[i], points[j] = points[j], points[i] })
+	c := circle{lat: points[0][0], lng: points[0][1]}
+	for i := 1; i < len(points); i++ {
+		if c.contains(points[i]) {
+			continue
+		}
+		c = circle{lat: points[i][0], lng: points[i][1]}
+		for j := 0; j < i; j++ {
+			if c.contains(points[j]) {
+				continue
+			}
+			c = circleFrom2(points[i], points[j])
+			for k := 0; k < j; k++ {
+				if !c.contains(points[k]) {
+					c = circleFrom3(points[i], points[j], points[k])
+				}
+			}
+		}
+	}
+	center := []float64{c.lat, c.lng}
+	for _, p := range coords {
+		radiusMeters = math.Max(radiusMeters, haversine(center, p))
+	}
+	return c.lat, c.lng, radiusMeters, nil
+}
+
+// EncodeGreatCircle returns the encoding, using the default codec, of segments
+// plus one coordinates evenly spaced along the shorter great circle arc from
+// from to to, which are (lat, lng) pairs. segments less than one is treated as
+// one. If from and to coincide then the result is a single coordinate. If from
+// and to are antipodal then every great circle through them is equally short
+// and the one passing closest to the North Pole is used.
+func EncodeGreatCircle(from, to []float64, segments int) []byte {
+	if segments < 1 {
+		segments = 1
+	}
+	toVector := func(p []float64) [3]float64 {
+		phi, lambda := radians(p[0]), radians(p[1])
+		return [3]float64{math.Cos(phi) * math.Cos(lambda), math.Cos(phi) * math.Sin(lambda), math.Sin(phi)}
+	}
+	v, u := toVector(from), toVector(to)
+	dot := v[0]*u[0] + v[1]*u[1] + v[2]*u[2]
+	omega := math.Acos(math.Max(-1, math.Min(1, dot)))
+	if omega == 0 {
+		return EncodeCoords([][]float64{from})
+	}
+
+	// w is the unit vector perpendicular to v in the plane of the great circle.
+	var w [3]float64
+	if sinOmega := math.Sin(omega); sinOmega > 1e-12 {
+		for i := range w {
+			w[i] = (u[i] - dot*v[i]) / sinOmega
+		}
+	} else {
+		w = [3]float64{-v[2] * v[0], -v[2] * v[1], 1 - v[2]*v[2]}
+		if norm := math.Sqrt(w[0]*w[0] + w[1]*w[1] + w[2]*w[2]); norm > 1e-12 {
+			for i := range w {
+				w[i] /= norm
+			}
+		} else {
+			w = [3]float64{1, 0, 0}
+		}
+	}
+
+	coords := make([][]float64, 0, segments+1)
+	coords = append(coords, from)
+	for i := 1; i < segments; i++ {
+		theta := omega * float64(i) / float64(segments)
+		var p [3]float64
+		for j := range p {
+			p[j] = math.Cos(theta)*v[j] + math.Sin(theta)*w[j]
+		}
+		coords = append(coords, []float64{
+			degrees(math.Atan2(p[2], math.Hypot(p[0], p[1]))),
+			degrees(math.Atan2(p[1], p[0])),
+		})
+	}
+	coords = append(coords, to)
+	return EncodeCoords(coords)
+}
+
+// resample returns samples coordinates spaced at equal great circle distances
+// along coords, including its first and last coordinates. samples must be at
+// least two.
+func resample(coords [][]float64, samples int) [][]float64 {
+	cumulative := make([]float64, len(coords))
+	for i := 1; i < len(coords); i++ {
+		cumulative[i] = cumulative[i-1] + haversine(coords[i-1], coords[i])
+	}
+	total := cumulative[len(cumulative)-1]
+	resampled := make([][]float64, samples)
+	i := 0
+	for k := range resampled {
+		target := total * float64(k) / float64(samples-1)
+		for i < len(coords)-2 && cumulative[i+1] < target {
+			i++
+		}
+		switch {
+		case k == samples-1:
+			resampled[k] = append([]float64(nil), coords[len(coords)-1]...)
+		case i+1 == len(coords) || cumulative[i+1] == cumulative[i]:
+			resampled[k] = append([]float64(nil), coords[i]...)
+		default:
+			t := (target - cumulative[i]) / (cumulative[i+1] - cumulative[i])
+			resampled[k] = interpolate(coords[i], coords[i+1], math.Max(0, math.Min(1, t)))
+		}
+	}
+	return resampled
+}
+
+// DecodeDeviation decodes the polylines a and b using the default codec,
+// resamples each to samples coordinates spaced at equal distances along it,
+// and returns the great circle distances in meters between corresponding
+// resampled coordinates, and any error. samples must be at least two.
+func DecodeDeviation(a, b []byte, samples int) ([]float64, error) {
+	if samples < 2 {
+		return nil, errInvalidSamples
+	}
+	aCoords, _, err := DecodeCoords(a)
+	if err != nil {
+		return nil, err
+	}
+	bCoords, _, err := DecodeCoords(b)
+	if err != nil {
+		return nil, err
+	}
+	aResampled, bResampled := resample(aCoords, samples), resample(bCoords, samples)
+	deviations := make([]float64, samples)
+	for i := range deviations {
+		deviations[i] = haversine(aResampled[i], bResampled[i])
+	}
+	return deviations, nil
+}
+
+// nearestDistance returns the smallest great circle cross-track distance in
+// meters from p to any segment of coords, or to its only coordinate.
+func nearestDistance(p []float64, coords [][]float64) float64 {
+	if len(coords) == 1 {
+		return haversine(p, coords[0])
+	}
+	d := math.Inf(1)
+	for i := 1; i < len(coords); i++ {
+		d = math.Min(d, crossTrackDistance(p, coords[i-1], coords[i]))
+	}
+	return d
+}
+
+// WithinCorridor decodes the polylines candidate and reference using the
+// default codec and returns whether every coordinate of candidate is within
+// bufferMeters of reference, measured as the great circle cross-track distance
+// to the nearest segment of reference, and any error.
+func WithinCorridor(candidate, reference []byte, bufferMeters float64) (bool, error) {
+	candidateCoords, _, err := DecodeCoords(candidate)
+	if err != nil {
+		return false, err
+	}
+	referenceCoords, _, err := DecodeCoords(reference)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range candidateCoords {
+		if nearestDistance(p, referenceCoords) > bufferMeters {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// SharedLength decodes the polylines a and b using the default codec and
+// returns an estimate of the length in meters of a that lies within
+// toleranceMeters of b, and any error. A segment of a counts towards the
+// shared length if both of its endpoints and its midpoint are within
+// toleranceMeters of b.
+func SharedLength(a, b []byte, toleranceMeters float64) (float64, error) {
+	aCoords, _, err := DecodeCoords(a)
+	if err != nil {
+		return 0, err
+	}
+	bCoords, _, err := DecodeCoords(b)
+	if err != nil {
+		return 0, err
+	}
+	within := make([]bool, len(aCoords))
+	for i, p := range aCoords {
+		within[i] = nearestDistance(p, bCoords) <= toleranceMeters
+	}
+	length := 0.0
+	for i := 1; i < len(aCoords); i++ {
+		if !within[i-1] || !within[i] {
+			continue
+		}
+		p0, p1 := aCoords[i-1], aCoords[i]
+		mid := []float64{(p0[0] + p1[0]) / 2, (p0[1] + p1[1]) / 2}
+		if nearestDistance(mid, bCoords) <= toleranceMeters {
+			length += haversine(p0, p1)
+		}
+	}
+	return length, nil
+}
+
+// maxMiter is the greatest ratio of the distance of a mitered vertex from the
+// original vertex to the offset distance before DecodeOffset bevels the join
+// instead.
+const maxMiter = 4
+
+// DecodeOffset decodes an array of coordinates from buf using the default
+// codec and returns the polyline parallel to it at a perpendicular distance of
+// offsetMeters to the right, or to the left if offsetMeters is negative, and
+// any error. Corners are mitered, except for very sharp corners which are
+// bevelled. Offsets are computed in a local equirectangular projection
+// centered on the first coordinate, so are only accurate for polylines that
+// are small compared to the Earth. Self-intersections at tight corners are
+// not removed. Repeated coordinates are ignored and polylines with fewer than
+// two distinct coordinates are returned unchanged.
+func DecodeOffset(buf []byte, offsetMeters float64) ([][]float64, error) {
+	coords, _, err := DecodeCoords(buf)
+	if err != nil {
+		return nil, err
+	}
+	lat0, lng0 := coords[0][0], coords[0][1]
+	k := earthRadius * math.Pi / 180
+	kx := k * math.Cos(radians(lat0))
+	points := make([][2]float64, 0, len(coords))
+	for _, coord := range coords {
+		p := [2]float64{kx * (coord[1] - lng0), k * (coord[0] - lat0)}
+		if len(points) > 0 && p == points[len(points)-1] {
+			continue
+		}
+		points = append(points, p)
+	}
+	if len(points) < 2 {
+		return coords, nil
+	}
+	normals := make([][2]float64, len(points)-1)
+	for i := range normals {
+		dx, dy := points[i+1][0]-points[i][0], points[i+1][1]-points[i][1]
+		d := math.Hypot(dx, dy)
+		normals[i] = [2]float64{dy / d, -dx / d}
+	}
+	result := make([][]float64, 0, len(points))
+	add := func(p, n [2]float64, scale float64) {
+		x := p[0] + scale*n[0]
+		y := p[1] + scale*n[1]
+		result = append(result, []float64{lat0 + y/k, lng0 + x/kx})
+	}
+	add(points[0], normals[0], offsetMeters)
+	for i := 1; i < len(points)-1; i++ {
+		n0, n1 := normals[i-1], normals[i]
+		dot := n0[0]*n1[0] + n0[1]*n1[1]
+		if 1+dot < 2.0/(maxMiter*maxMiter) {
+			add(points[i], n0, offsetMeters)
+			add(points[i], n1, offsetMeters)
+			continue
+		}
+		add(points[i], [2]float64{n0[0] + n1[0], n0[1] + n1[1]}, offsetMeters/(1+dot))
+	}
+	add(points[len(points)-1], normals[len(normals)-1], offsetMeters)
+	return result, nil
+}
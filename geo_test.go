@@ -0,0 +1,1124 @@
+package polyline
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeConvexHull(t *testing.T) {
+	for _, tc := range []struct {
+		cs   [][]float64
+		hull [][]float64
+	}{
+		{
+			cs:   [][]float64{{0, 0}, {1, 1}, {0, 2}, {2, 2}, {2, 0}, {1, 1}},
+			hull: [][]float64{{0, 0}, {0, 2}, {2, 2}, {2, 0}, {0, 0}},
+		},
+		{
+			cs:   [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}},
+			hull: [][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}},
+		},
+		{
+			cs:   [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			hull: [][]float64{{43.252, -126.453}, {38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			cs:   [][]float64{{0, 0}, {1, 1}, {2, 2}},
+			hull: [][]float64{{0, 0}, {2, 2}, {0, 0}},
+		},
+		{
+			cs:   [][]float64{{0, 0}, {1, 1}, {0, 0}},
+			hull: [][]float64{{0, 0}, {1, 1}, {0, 0}},
+		},
+		{
+			cs:   [][]float64{{38.5, -120.2}},
+			hull: [][]float64{{38.5, -120.2}},
+		},
+	} {
+		got, err := DecodeConvexHull(EncodeCoords(tc.cs))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.hull, got)
+	}
+}
+
+func TestDecodeConvexHullErrors(t *testing.T) {
+	_, err := DecodeConvexHull([]byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestClipToBounds(t *testing.T) {
+	for _, tc := range []struct {
+		cs   [][]float64
+		want [][]float64
+	}{
+		{
+			cs:   [][]float64{{0, 0}, {1, 1}, {5, 5}, {2, 2}, {3, 3}, {9, 9}},
+			want: [][]float64{{1, 1}, {5, 5}, {2, 2}, {3, 3}},
+		},
+		{
+			cs:   [][]float64{{1, 1}, {3, 3}},
+			want: [][]float64{{1, 1}, {3, 3}},
+		},
+		{
+			cs:   [][]float64{{0, 0}, {2, 2}, {9, 9}},
+			want: [][]float64{{2, 2}},
+		},
+		{
+			cs:   [][]float64{{0, 0}, {9, 9}},
+			want: nil,
+		},
+	} {
+		got, err := defaultCodec.ClipToBounds(EncodeCoords(tc.cs), 1, 1, 3, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, EncodeCoords(tc.want), got)
+	}
+}
+
+func TestClipToBoundsErrors(t *testing.T) {
+	_, err := Codec{Dim: 3, Scale: 1e5}.ClipToBounds([]byte("???"), 0, 0, 1, 1)
+	assert.Equal(t, errDimensionalMismatch, err)
+	_, err = defaultCodec.ClipToBounds([]byte("?"), 0, 0, 1, 1)
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeClip(t *testing.T) {
+	for _, tc := range []struct {
+		cs    [][]float64
+		parts [][][]float64
+	}{
+		{
+			cs:    [][]float64{{1, 1}, {2, 2}, {3, 3}},
+			parts: [][][]float64{{{1, 1}, {2, 2}, {3, 3}}},
+		},
+		{
+			cs:    [][]float64{{0, 2}, {4, 2}},
+			parts: [][][]float64{{{1, 2}, {3, 2}}},
+		},
+		{
+			cs:    [][]float64{{2, 2}, {2, 4}, {2, 5}, {2, 2}},
+			parts: [][][]float64{{{2, 2}, {2, 3}}, {{2, 3}, {2, 2}}},
+		},
+		{
+			cs:    [][]float64{{0, 0}, {2, 2}, {2, 5}},
+			parts: [][][]float64{{{1, 1}, {2, 2}, {2, 3}}},
+		},
+		{
+			cs:    [][]float64{{0, 0}, {0, 5}, {5, 5}},
+			parts: nil,
+		},
+		{
+			cs:    [][]float64{{2, 2}},
+			parts: [][][]float64{{{2, 2}}},
+		},
+		{
+			cs:    [][]float64{{5, 5}},
+			parts: nil,
+		},
+		{
+			cs:    [][]float64{{2, 2}, {3, 3}, {4, 4}},
+			parts: [][][]float64{{{2, 2}, {3, 3}}},
+		},
+		{
+			cs:    [][]float64{{4, 2}, {3, 3}, {4, 4}},
+			parts: nil,
+		},
+		{
+			cs:    [][]float64{{4, 2}, {2, 4}},
+			parts: nil,
+		},
+		{
+			cs:    [][]float64{{2, 2}, {3, 3}, {4, 4}, {3, 3}, {2, 2}},
+			parts: [][][]float64{{{2, 2}, {3, 3}}, {{3, 3}, {2, 2}}},
+		},
+		{
+			cs:    [][]float64{{2, 2}, {2, 2}},
+			parts: [][][]float64{{{2, 2}, {2, 2}}},
+		},
+	} {
+		got, err := DecodeClip(EncodeCoords(tc.cs), 1, 1, 3, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.parts, got)
+	}
+}
+
+func TestDecodeBearings(t *testing.T) {
+	for _, tc := range []struct {
+		cs       [][]float64
+		bearings []float64
+	}{
+		{
+			cs:       [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}},
+			bearings: []float64{0, 89.99127357532927, 180, 270, 270},
+		},
+		{
+			cs:       [][]float64{{0, 0}, {1, 1}},
+			bearings: []float64{44.99563645534486, 44.99563645534486},
+		},
+		{
+			cs:       [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			bearings: []float64{345.52033189376533, 303.77541428787833, 303.77541428787833},
+		},
+	} {
+		got, err := DecodeBearings(EncodeCoords(tc.cs))
+		assert.NoError(t, err)
+		assert.InDeltaSlice(t, tc.bearings, got, 1e-6)
+	}
+}
+
+func TestDecodeBearingsSingle(t *testing.T) {
+	got, err := DecodeBearings([]byte("_p~iF~ps|U"))
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.True(t, math.IsNaN(got[0]))
+}
+
+func TestEncodeBBox(t *testing.T) {
+	got := defaultCodec.EncodeBBox(nil, 1, 2, 3, 4)
+	want := EncodeCoords([][]float64{{1, 2}, {1, 4}, {3, 4}, {3, 2}, {1, 2}})
+	assert.Equal(t, want, got)
+	closed, err := defaultCodec.IsClosed(got)
+	assert.NoError(t, err)
+	assert.True(t, closed)
+	assert.Panics(t, func() { Codec{Dim: 3, Scale: 1e5}.EncodeBBox(nil, 1, 2, 3, 4) })
+}
+
+func TestVincenty(t *testing.T) {
+	for _, tc := range []struct {
+		p0, p1 []float64
+		d      float64
+		ok     bool
+	}{
+		{
+			p0: []float64{-37.95103341666667, 144.42486788888888},
+			p1: []float64{-37.65282113888889, 143.92649552777777},
+			d:  54972.271,
+			ok: true,
+		},
+		{
+			p0: []float64{10, 20},
+			p1: []float64{10, 20},
+			d:  0,
+			ok: true,
+		},
+		{
+			p0: []float64{0, 0},
+			p1: []float64{0.5, 179.7},
+			ok: false,
+		},
+	} {
+		d, ok := vincenty(tc.p0, tc.p1)
+		assert.Equal(t, tc.ok, ok)
+		assert.InDelta(t, tc.d, d, 1e-3)
+	}
+}
+
+func TestDecodeMeanBearing(t *testing.T) {
+	for _, tc := range []struct {
+		coords [][]float64
+		want   float64
+	}{
+		{
+			coords: [][]float64{{0, 0}, {0.01, 0}},
+			want:   0,
+		},
+		{
+			coords: [][]float64{{0, 0}, {0, 0.01}},
+			want:   90,
+		},
+		{
+			coords: [][]float64{{0, 0}, {0.01, 0.0001}, {0.02, 0}},
+			want:   0,
+		},
+		{
+			coords: [][]float64{{0, 0}, {0.01, -0.0001}, {0.01, -0.0001}, {0.02, -0.0002}},
+			want:   359.427,
+		},
+		{
+			coords: [][]float64{{0, 0}, {0.02, 0}, {0.02, 0.01}},
+			want:   26.565,
+		},
+	} {
+		got, err := DecodeMeanBearing(EncodeCoords(tc.coords))
+		assert.NoError(t, err)
+		assert.InDelta(t, tc.want, got, 1e-3)
+	}
+	for _, tc := range []struct {
+		s   string
+		err error
+	}{
+		{s: "_p~iF", err: errUnterminatedSequence},
+		{s: "_p~iF~ps|U", err: errTooFewCoords},
+		{s: "_p~iF~ps|U??", err: errTooFewCoords},
+	} {
+		_, err := DecodeMeanBearing([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+	}
+}
+
+func TestDecodeLength(t *testing.T) {
+	for _, tc := range []struct {
+		c      Codec
+		cs     [][]float64
+		length float64
+	}{
+		{
+			c:      defaultCodec,
+			cs:     [][]float64{{0, 0}, {0, 1}},
+			length: earthRadius * math.Pi / 180,
+		},
+		{
+			c:      Codec{Dim: 2, Scale: 1e7, Radians: true},
+			cs:     [][]float64{{0, 0}, {0, math.Pi / 180}},
+			length: earthRadius * math.Pi / 180,
+		},
+		{
+			c:      Codec{Dim: 2, Scale: 1, CoordSystem: Projected},
+			cs:     [][]float64{{500000, 4649776}, {500003, 4649780}, {500003, 4649790}},
+			length: 15,
+		},
+		{
+			c:      Codec{Dim: 3, Scale: 1, CoordSystem: Projected},
+			cs:     [][]float64{{0, 0, 0}, {1, 2, 2}},
+			length: 3,
+		},
+		{
+			c:      Codec{Dim: 3, Scale: 1e5},
+			cs:     [][]float64{{0, 0, 100}, {0, 1, 200}},
+			length: earthRadius * math.Pi / 180,
+		},
+		{
+			c:      defaultCodec,
+			cs:     [][]float64{{38.5, -120.2}},
+			length: 0,
+		},
+	} {
+		got, err := tc.c.DecodeLength(tc.c.EncodeCoords(nil, tc.cs))
+		assert.NoError(t, err)
+		assert.InDelta(t, tc.length, got, 1e-2)
+	}
+	_, err := defaultCodec.DecodeLength([]byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeNearestVertex(t *testing.T) {
+	for _, tc := range []struct {
+		c     Codec
+		cs    [][]float64
+		coord []float64
+		index int
+		dist  float64
+	}{
+		{
+			c:     defaultCodec,
+			cs:    [][]float64{{0, 0}, {0, 1}, {0, 2}},
+			coord: []float64{0, 0.9},
+			index: 1,
+			dist:  0.1 * earthRadius * math.Pi / 180,
+		},
+		{
+			c:     defaultCodec,
+			cs:    [][]float64{{38.5, -120.2}},
+			coord: []float64{38.5, -120.2},
+			index: 0,
+			dist:  0,
+		},
+		{
+			c:     defaultCodec,
+			cs:    [][]float64{{0, 0}, {0, 1}, {0, 0}},
+			coord: []float64{0, 0},
+			index: 0,
+			dist:  0,
+		},
+		{
+			c:     Codec{Dim: 3, Scale: 1, CoordSystem: Projected},
+			cs:    [][]float64{{0, 0, 0}, {10, 10, 10}, {1, 2, 2}},
+			coord: []float64{0, 0, 0.5},
+			index: 0,
+			dist:  0.5,
+		},
+		{
+			c:     Codec{Dim: 2, Scale: 1, CoordSystem: Projected},
+			cs:    [][]float64{{0, 0}, {10, 10}, {3, 4}},
+			coord: []float64{3, 5},
+			index: 2,
+			dist:  1,
+		},
+	} {
+		index, dist, err := tc.c.DecodeNearestVertex(tc.c.EncodeCoords(nil, tc.cs), tc.coord)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.index, index)
+		assert.InDelta(t, tc.dist, dist, 1e-6)
+	}
+	for _, s := range []string{"", "_p~iF", "_p~iF~ps|U_p~iF"} {
+		_, _, err := defaultCodec.DecodeNearestVertex([]byte(s), []float64{0, 0})
+		assert.Equal(t, errUnterminatedSequence, err)
+	}
+	for _, coord := range [][]float64{nil, {1}, {1, 2, 3}} {
+		_, _, err := defaultCodec.DecodeNearestVertex([]byte("_p~iF~ps|U"), coord)
+		assert.Equal(t, errDimensionalMismatch, err)
+	}
+}
+
+func TestDecodeLengthVincenty(t *testing.T) {
+	for _, tc := range []struct {
+		cs     [][]float64
+		length float64
+		delta  float64
+	}{
+		{
+			cs:     [][]float64{{-37.95103, 144.42487}, {-37.65282, 143.9265}},
+			length: 54972.271,
+			delta:  1,
+		},
+		{
+			cs:     [][]float64{{-37.95103, 144.42487}, {-37.65282, 143.9265}, {-37.95103, 144.42487}},
+			length: 2 * 54972.271,
+			delta:  2,
+		},
+		{
+			cs:     [][]float64{{0, 0}, {0.5, 179.7}},
+			length: 1.9950277343496524e+07,
+			delta:  1e-3,
+		},
+		{
+			cs:     [][]float64{{0, 0}},
+			length: 0,
+		},
+	} {
+		got, err := DecodeLengthVincenty(EncodeCoords(tc.cs))
+		assert.NoError(t, err)
+		assert.InDelta(t, tc.length, got, tc.delta)
+	}
+	_, err := DecodeLengthVincenty([]byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeCoordsThin(t *testing.T) {
+	for _, tc := range []struct {
+		cs        [][]float64
+		minMeters float64
+		want      [][]float64
+	}{
+		{
+			cs:        [][]float64{{0, 0}, {0, 0.00001}, {0, 0.001}, {0, 0.00101}, {0, 0.002}, {0, 0.00201}},
+			minMeters: 50,
+			want:      [][]float64{{0, 0}, {0, 0.001}, {0, 0.002}, {0, 0.00201}},
+		},
+		{
+			cs:        [][]float64{{0, 0}, {0, 0.00001}, {0, 0.001}},
+			minMeters: 0,
+			want:      [][]float64{{0, 0}, {0, 0.00001}, {0, 0.001}},
+		},
+		{
+			cs:        [][]float64{{0, 0}, {0, 0.00001}},
+			minMeters: 1000,
+			want:      [][]float64{{0, 0}, {0, 0.00001}},
+		},
+		{
+			cs:        [][]float64{{0, 0}},
+			minMeters: 1000,
+			want:      [][]float64{{0, 0}},
+		},
+	} {
+		got, err := DecodeCoordsThin(EncodeCoords(tc.cs), tc.minMeters)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+	_, err := DecodeCoordsThin([]byte("_p~iF"), 1)
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestSimplifyToBudget(t *testing.T) {
+	zigzag := [][]float64{{0, 0}, {1, 1}, {0, 2}, {5, 3}, {0, 4}, {2, 5}, {0, 6}}
+	for _, tc := range []struct {
+		coords    [][]float64
+		maxPoints int
+		want      [][]float64
+	}{
+		{
+			coords:    zigzag,
+			maxPoints: 7,
+			want:      zigzag,
+		},
+		{
+			coords:    zigzag,
+			maxPoints: 3,
+			want:      [][]float64{{0, 0}, {5, 3}, {0, 6}},
+		},
+		{
+			coords:    zigzag,
+			maxPoints: 4,
+			want:      [][]float64{{0, 0}, {5, 3}, {0, 6}},
+		},
+		{
+			coords:    zigzag,
+			maxPoints: 5,
+			want:      [][]float64{{0, 0}, {5, 3}, {0, 4}, {2, 5}, {0, 6}},
+		},
+		{
+			coords:    zigzag,
+			maxPoints: 6,
+			want:      [][]float64{{0, 0}, {0, 2}, {5, 3}, {0, 4}, {2, 5}, {0, 6}},
+		},
+		{
+			coords:    zigzag,
+			maxPoints: 2,
+			want:      [][]float64{{0, 0}, {0, 6}},
+		},
+		{
+			coords:    zigzag,
+			maxPoints: 0,
+			want:      [][]float64{{0, 0}, {0, 6}},
+		},
+		{
+			coords:    [][]float64{{0, 0}, {1, 1}, {2, 2}, {3, 3}},
+			maxPoints: 3,
+			want:      [][]float64{{0, 0}, {3, 3}},
+		},
+		{
+			coords:    [][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}},
+			maxPoints: 3,
+			want:      [][]float64{{0, 0}, {1, 1}, {0, 0}},
+		},
+	} {
+		assert.Equal(t, tc.want, SimplifyToBudget(tc.coords, tc.maxPoints))
+	}
+}
+
+func TestCrossTrackDistance(t *testing.T) {
+	for _, tc := range []struct {
+		p, a, b []float64
+		want    float64
+	}{
+		{p: []float64{0.01, 1}, a: []float64{0, 0}, b: []float64{0, 2}, want: 1111.951},
+		{p: []float64{-0.01, 1}, a: []float64{0, 0}, b: []float64{0, 2}, want: 1111.951},
+		{p: []float64{0, -1}, a: []float64{0, 0}, b: []float64{0, 2}, want: 111195.080},
+		{p: []float64{0, 3}, a: []float64{0, 0}, b: []float64{0, 2}, want: 111195.080},
+		{p: []float64{0, 1}, a: []float64{0, 0}, b: []float64{0, 0}, want: 111195.080},
+		{p: []float64{80, 5}, a: []float64{80, 0}, b: []float64{80, 10}, want: 4146.377},
+	} {
+		assert.InDelta(t, tc.want, crossTrackDistance(tc.p, tc.a, tc.b), 1e-3)
+	}
+}
+
+func TestSimplifyGeo(t *testing.T) {
+	parallel := [][]float64{{80, 0}, {80, 5}, {80, 10}}
+	for _, tc := range []struct {
+		coords        [][]float64
+		epsilonMeters float64
+		want          [][]float64
+	}{
+		{
+			coords:        parallel,
+			epsilonMeters: 5000,
+			want:          [][]float64{{80, 0}, {80, 10}},
+		},
+		{
+			coords:        parallel,
+			epsilonMeters: 3000,
+			want:          parallel,
+		},
+		{
+			coords:        [][]float64{{0, 0}, {0.011, 1}, {0.02, 2}, {0, 3}},
+			epsilonMeters: 1000,
+			want:          [][]float64{{0, 0}, {0.02, 2}, {0, 3}},
+		},
+		{
+			coords:        [][]float64{{0, 0}, {0, 1}},
+			epsilonMeters: 1000,
+			want:          [][]float64{{0, 0}, {0, 1}},
+		},
+	} {
+		assert.Equal(t, tc.want, SimplifyGeo(tc.coords, tc.epsilonMeters))
+	}
+}
+
+func TestDecodeGeodesicArea(t *testing.T) {
+	square := earthRadius * earthRadius * radians(1) * math.Sin(radians(1))
+	for _, tc := range []struct {
+		coords [][]float64
+		want   float64
+	}{
+		{
+			coords: [][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}},
+			want:   square,
+		},
+		{
+			coords: [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}},
+			want:   square,
+		},
+		{
+			coords: [][]float64{{0, 0}, {0, 1}, {0, 2}},
+			want:   0,
+		},
+		{
+			coords: [][]float64{{0, 0}},
+			want:   0,
+		},
+	} {
+		got, err := DecodeGeodesicArea(EncodeCoords(tc.coords))
+		assert.NoError(t, err)
+		assert.InDelta(t, tc.want, got, 1e-3)
+	}
+	_, err := DecodeGeodesicArea([]byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeContains(t *testing.T) {
+	square := EncodeCoords([][]float64{{0, 0}, {0, 2}, {2, 2}, {2, 0}})
+	notch := EncodeCoords([][]float64{{0, 0}, {0, 4}, {4, 4}, {4, 0}, {2, 2}, {0, 0}})
+	for _, tc := range []struct {
+		buf      []byte
+		lat, lng float64
+		want     bool
+	}{
+		{buf: square, lat: 1, lng: 1, want: true},
+		{buf: square, lat: 3, lng: 1, want: false},
+		{buf: square, lat: 1, lng: -1, want: false},
+		{buf: square, lat: 1, lng: 3, want: false},
+		{buf: square, lat: 0, lng: 1, want: true},
+		{buf: square, lat: 2, lng: 2, want: true},
+		{buf: square, lat: 1, lng: 0, want: true},
+		{buf: notch, lat: 3, lng: 2, want: true},
+		{buf: notch, lat: 2, lng: 1, want: false},
+		{buf: notch, lat: 1, lng: 0.5, want: false},
+		{buf: notch, lat: 1, lng: 2, want: true},
+		{buf: notch, lat: 2, lng: 2, want: true},
+	} {
+		got, err := DecodeContains(tc.buf, tc.lat, tc.lng)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got, "lat=%v lng=%v", tc.lat, tc.lng)
+	}
+	_, err := DecodeContains([]byte("_p~iF"), 0, 0)
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeSinuosity(t *testing.T) {
+	for _, tc := range []struct {
+		coords [][]float64
+		want   float64
+	}{
+		{coords: [][]float64{{0, 0}, {0, 1}}, want: 1},
+		{coords: [][]float64{{0, 0}, {0, 1}, {0, 2}}, want: 1},
+		{coords: [][]float64{{0, 0}, {0, 1}, {0, 0}, {0, 1}}, want: 3},
+		{coords: [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}}, want: 3},
+		{coords: [][]float64{{0, 0}, {0, 1}, {0, 0}}, want: math.Inf(1)},
+		{coords: [][]float64{{0, 0}}, want: math.Inf(1)},
+	} {
+		got, err := DecodeSinuosity(EncodeCoords(tc.coords))
+		assert.NoError(t, err)
+		if math.IsInf(tc.want, 1) {
+			assert.True(t, math.IsInf(got, 1))
+		} else {
+			assert.InDelta(t, tc.want, got, 1e-3)
+		}
+	}
+	_, err := DecodeSinuosity([]byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestGuessScale(t *testing.T) {
+	cs := [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}
+	for _, tc := range []struct {
+		buf      []byte
+		lat, lng float64
+		want     float64
+	}{
+		{buf: EncodeCoords(cs), lat: 38.5, lng: -120.2, want: 1e5},
+		{buf: EncodeCoords(cs), lat: 38.50001, lng: -120.19999, want: 1e5},
+		{buf: Codec{Dim: 2, Scale: 1e6}.EncodeCoords(nil, cs), lat: 38.5, lng: -120.2, want: 1e6},
+		{buf: Codec{Dim: 2, Scale: 1e6}.EncodeCoords(nil, cs), lat: 38.5001, lng: -120.2001, want: 1e6},
+		{buf: EncodeCoords([][]float64{{0.5, 0.5}}), lat: 0.05, lng: 0.05, want: 1e6},
+	} {
+		got, err := GuessScale(tc.buf, tc.lat, tc.lng)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+	for _, s := range []string{"", "_p~iF"} {
+		_, err := GuessScale([]byte(s), 0, 0)
+		assert.Equal(t, errUnterminatedSequence, err)
+	}
+}
+
+func TestDecodeEnclosingCircle(t *testing.T) {
+	for _, tc := range []struct {
+		coords       [][]float64
+		centerLat    float64
+		centerLng    float64
+		radiusMeters float64
+	}{
+		{
+			coords:       [][]float64{{1, 2}},
+			centerLat:    1,
+			centerLng:    2,
+			radiusMeters: 0,
+		},
+		{
+			coords:       [][]float64{{0, -1}, {0, 1}},
+			centerLat:    0,
+			centerLng:    0,
+			radiusMeters: 111195.080,
+		},
+		{
+			coords:       [][]float64{{0, -1}, {0.5, 0}, {0, 1}, {-0.5, 0.2}, {0, 0}},
+			centerLat:    0,
+			centerLng:    0,
+			radiusMeters: 111195.080,
+		},
+		{
+			coords:       [][]float64{{-1, 0}, {0, -1}, {1, 0}, {0, 1}, {0.5, 0.5}},
+			centerLat:    0,
+			centerLng:    0,
+			radiusMeters: 111195.080,
+		},
+		{
+			coords:       [][]float64{{0, 0}, {0, 1}, {0, 2}, {0, 3}},
+			centerLat:    0,
+			centerLng:    1.5,
+			radiusMeters: 166792.620,
+		},
+	} {
+		centerLat, centerLng, radiusMeters, err := DecodeEnclosingCircle(EncodeCoords(tc.coords))
+		assert.NoError(t, err)
+		assert.InDelta(t, tc.centerLat, centerLat, 1e-9)
+		assert.InDelta(t, tc.centerLng, centerLng, 1e-9)
+		assert.InDelta(t, tc.radiusMeters, radiusMeters, 1e-3)
+	}
+	_, _, _, err := DecodeEnclosingCircle([]byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestCircleFrom3(t *testing.T) {
+	for _, tc := range []struct {
+		a, b, c []float64
+		want    circle
+	}{
+		{a: []float64{0, 1}, b: []float64{1, 0}, c: []float64{0, -1}, want: circle{lat: 0, lng: 0, r: 1}},
+		{a: []float64{0, 0}, b: []float64{0, 2}, c: []float64{0, 1}, want: circle{lat: 0, lng: 1, r: 1}},
+	} {
+		got := circleFrom3(tc.a, tc.b, tc.c)
+		assert.InDelta(t, tc.want.lat, got.lat, 1e-12)
+		assert.InDelta(t, tc.want.lng, got.lng, 1e-12)
+		assert.InDelta(t, tc.want.r, got.r, 1e-12)
+	}
+}
+
+func TestEncodeGreatCircle(t *testing.T) {
+	for _, tc := range []struct {
+		from, to []float64
+		segments int
+		want     [][]float64
+	}{
+		{
+			from:     []float64{0, 0},
+			to:       []float64{0, 90},
+			segments: 4,
+			want:     [][]float64{{0, 0}, {0, 22.5}, {0, 45}, {0, 67.5}, {0, 90}},
+		},
+		{
+			from:     []float64{0, 0},
+			to:       []float64{90, 0},
+			segments: 2,
+			want:     [][]float64{{0, 0}, {45, 0}, {90, 0}},
+		},
+		{
+			from:     []float64{51.5, 0},
+			to:       []float64{40.7, -74},
+			segments: 4,
+			want:     [][]float64{{51.5, 0}, {53.76499, -20.40515}, {52.38047, -41.23336}, {47.71, -59.43429}, {40.7, -74}},
+		},
+		{
+			from:     []float64{51.5, 0},
+			to:       []float64{40.7, -74},
+			segments: 0,
+			want:     [][]float64{{51.5, 0}, {40.7, -74}},
+		},
+		{
+			from:     []float64{0, 0},
+			to:       []float64{0, 180},
+			segments: 2,
+			want:     [][]float64{{0, 0}, {90, 0}, {0, 180}},
+		},
+		{
+			from:     []float64{90, 0},
+			to:       []float64{-90, 0},
+			segments: 2,
+			want:     [][]float64{{90, 0}, {0, 0}, {-90, 0}},
+		},
+		{
+			from:     []float64{10, 20},
+			to:       []float64{10, 20},
+			segments: 4,
+			want:     [][]float64{{10, 20}},
+		},
+	} {
+		got, _, err := DecodeCoords(EncodeGreatCircle(tc.from, tc.to, tc.segments))
+		assert.NoError(t, err)
+		assert.True(t, CoordsApproxEqual(tc.want, got, 1e-5), "got %v, want %v", got, tc.want)
+	}
+}
+
+func TestDecodeTurnCount(t *testing.T) {
+	for _, tc := range []struct {
+		coords         [][]float64
+		minTurnDegrees float64
+		want           int
+	}{
+		{coords: [][]float64{{0, 0}}, minTurnDegrees: 45, want: 0},
+		{coords: [][]float64{{0, 0}, {0, 1}}, minTurnDegrees: 45, want: 0},
+		{coords: [][]float64{{0, 0}, {0, 1}, {0, 2}}, minTurnDegrees: 45, want: 0},
+		{coords: [][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 2}}, minTurnDegrees: 45, want: 2},
+		{coords: [][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 2}}, minTurnDegrees: 90, want: 0},
+		{coords: [][]float64{{0, 0}, {0, 1}, {0, 1}, {1, 1}}, minTurnDegrees: 45, want: 1},
+		{coords: [][]float64{{0, 0}, {0, 1}, {0, 0}}, minTurnDegrees: 170, want: 1},
+		{coords: [][]float64{{0, 0}, {0.1, 1}, {0, 2}}, minTurnDegrees: 5, want: 1},
+		{coords: [][]float64{{0, 0}, {0.1, 1}, {0, 2}}, minTurnDegrees: 15, want: 0},
+	} {
+		got, err := DecodeTurnCount(EncodeCoords(tc.coords), tc.minTurnDegrees)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+	_, err := DecodeTurnCount([]byte("_p~iF"), 45)
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeTotalTurning(t *testing.T) {
+	var circle [][]float64
+	for i := 0; i <= 36; i++ {
+		a := float64(i) * math.Pi / 18
+		circle = append(circle, []float64{0.01 * math.Sin(a), 0.01 * math.Cos(a)})
+	}
+	for _, tc := range []struct {
+		coords [][]float64
+		want   float64
+		delta  float64
+	}{
+		{
+			coords: [][]float64{{0, 0}},
+			want:   0,
+		},
+		{
+			coords: [][]float64{{0, 0}, {1, 1}},
+			want:   0,
+		},
+		{
+			coords: [][]float64{{0, 0}, {0, 1}, {0, 2}, {0, 3}},
+			want:   0,
+			delta:  1e-9,
+		},
+		{
+			coords: [][]float64{{0, 0}, {0, 1}, {0, 1}, {1, 1}, {1, 0}},
+			want:   math.Pi,
+			delta:  1e-3,
+		},
+		{
+			coords: circle,
+			want:   35 * math.Pi / 18,
+			delta:  1e-2,
+		},
+	} {
+		got, err := DecodeTotalTurning(EncodeCoords(tc.coords))
+		assert.NoError(t, err)
+		assert.InDelta(t, tc.want, got, tc.delta)
+	}
+	_, err := DecodeTotalTurning([]byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestEncodeCoordsAccuracy(t *testing.T) {
+	cs := [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}
+	for _, tc := range []struct {
+		maxErrorMeters float64
+		precision      int
+	}{
+		{maxErrorMeters: 1e6, precision: 0},
+		{maxErrorMeters: 1000, precision: 2},
+		{maxErrorMeters: 0.6, precision: 5},
+		{maxErrorMeters: 0.5, precision: 6},
+		{maxErrorMeters: 0.05, precision: 7},
+	} {
+		buf, precision, err := EncodeCoordsAccuracy(cs, tc.maxErrorMeters)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.precision, precision)
+		c := Codec{Dim: 2, Scale: math.Pow10(precision)}
+		assert.Equal(t, c.EncodeCoords(nil, cs), buf)
+	}
+	for _, maxErrorMeters := range []float64{0, -1, math.NaN(), 1e-6} {
+		_, _, err := EncodeCoordsAccuracy(cs, maxErrorMeters)
+		assert.Equal(t, errInvalidAccuracy, err)
+	}
+}
+
+func TestResample(t *testing.T) {
+	for _, tc := range []struct {
+		coords  [][]float64
+		samples int
+		want    [][]float64
+	}{
+		{
+			coords:  [][]float64{{0, 0}, {0, 2}},
+			samples: 3,
+			want:    [][]float64{{0, 0}, {0, 1}, {0, 2}},
+		},
+		{
+			coords:  [][]float64{{0, 0}, {0, 0.5}, {0, 3}},
+			samples: 4,
+			want:    [][]float64{{0, 0}, {0, 1}, {0, 2}, {0, 3}},
+		},
+		{
+			coords:  [][]float64{{0, 0}, {0, 1}, {0, 1}, {0, 2}},
+			samples: 2,
+			want:    [][]float64{{0, 0}, {0, 2}},
+		},
+		{
+			coords:  [][]float64{{1, 1}},
+			samples: 3,
+			want:    [][]float64{{1, 1}, {1, 1}, {1, 1}},
+		},
+	} {
+		got := resample(tc.coords, tc.samples)
+		assert.True(t, CoordsApproxEqual(tc.want, got, 1e-9), "got %v, want %v", got, tc.want)
+	}
+}
+
+func TestDecodeDeviation(t *testing.T) {
+	for _, tc := range []struct {
+		a, b    [][]float64
+		samples int
+		want    []float64
+	}{
+		{
+			a:       [][]float64{{0, 0}, {0, 2}},
+			b:       [][]float64{{0, 0}, {0, 1}, {0, 2}},
+			samples: 3,
+			want:    []float64{0, 0, 0},
+		},
+		{
+			a:       [][]float64{{0, 0}, {0, 2}},
+			b:       [][]float64{{0, 0}, {1, 1}, {0, 2}},
+			samples: 3,
+			want:    []float64{0, 111195.080, 0},
+		},
+		{
+			a:       [][]float64{{0, 0}, {0, 1}},
+			b:       [][]float64{{1, 0}, {1, 1}},
+			samples: 2,
+			want:    []float64{111195.080, 111195.080},
+		},
+	} {
+		got, err := DecodeDeviation(EncodeCoords(tc.a), EncodeCoords(tc.b), tc.samples)
+		assert.NoError(t, err)
+		assert.InDeltaSlice(t, tc.want, got, 1e-3)
+	}
+}
+
+func TestDecodeDeviationErrors(t *testing.T) {
+	valid := []byte("_p~iF~ps|U")
+	for _, tc := range []struct {
+		a, b    []byte
+		samples int
+		err     error
+	}{
+		{a: valid, b: valid, samples: 1, err: errInvalidSamples},
+		{a: []byte("_p~iF"), b: valid, samples: 2, err: errUnterminatedSequence},
+		{a: valid, b: []byte("_p~iF"), samples: 2, err: errUnterminatedSequence},
+	} {
+		_, err := DecodeDeviation(tc.a, tc.b, tc.samples)
+		assert.Equal(t, tc.err, err)
+	}
+}
+
+func TestConcatSimplify(t *testing.T) {
+	for _, tc := range []struct {
+		segments [][][]float64
+		epsilon  float64
+		want     [][]float64
+	}{
+		{
+			segments: [][][]float64{
+				{{0, 0}, {0, 1}},
+				{{0, 1}, {0, 2}, {1, 3}},
+				{{1, 3}, {1, 4}},
+			},
+			epsilon: 0,
+			want:    [][]float64{{0, 0}, {0, 2}, {1, 3}, {1, 4}},
+		},
+		{
+			segments: [][][]float64{
+				{{0, 0}, {0, 1}},
+				{{0, 1}, {0.001, 2}, {0, 3}},
+				{{0, 3}, {0, 4}},
+			},
+			epsilon: 0.01,
+			want:    [][]float64{{0, 0}, {0, 4}},
+		},
+		{
+			segments: [][][]float64{
+				nil,
+				{{0, 0}, {0, 1}},
+				nil,
+				{{0.000001, 1}, {1, 1}},
+			},
+			epsilon: 0,
+			want:    [][]float64{{0, 0}, {0, 1}, {1, 1}},
+		},
+	} {
+		var bufs [][]byte
+		for _, segment := range tc.segments {
+			bufs = append(bufs, EncodeCoords(segment))
+		}
+		got, err := defaultCodec.ConcatSimplify(bufs, tc.epsilon)
+		assert.NoError(t, err)
+		assert.Equal(t, string(EncodeCoords(tc.want)), string(got))
+	}
+}
+
+func TestConcatSimplifyErrors(t *testing.T) {
+	_, err := defaultCodec.ConcatSimplify([][]byte{[]byte("_p~iF~ps|U"), []byte("_p~iF")}, 0)
+	assert.Equal(t, errUnterminatedSequence, err)
+	_, err = Codec{Dim: 1, Scale: 1e5}.ConcatSimplify(nil, 0)
+	assert.Equal(t, errDimensionalMismatch, err)
+}
+
+func TestWithinCorridor(t *testing.T) {
+	reference := EncodeCoords([][]float64{{0, 0}, {0, 1}, {1, 1}})
+	for _, tc := range []struct {
+		candidate    [][]float64
+		reference    []byte
+		bufferMeters float64
+		want         bool
+	}{
+		{
+			candidate:    [][]float64{{0, 0}, {0, 1}, {1, 1}},
+			reference:    reference,
+			bufferMeters: 0.001,
+			want:         true,
+		},
+		{
+			candidate:    [][]float64{{0.005, 0}, {-0.005, 0.5}, {0.5, 1.005}},
+			reference:    reference,
+			bufferMeters: 600,
+			want:         true,
+		},
+		{
+			candidate:    [][]float64{{0.005, 0}, {-0.005, 0.5}, {0.5, 1.005}},
+			reference:    reference,
+			bufferMeters: 500,
+			want:         false,
+		},
+		{
+			candidate:    [][]float64{{0, -0.01}},
+			reference:    reference,
+			bufferMeters: 1000,
+			want:         false,
+		},
+		{
+			candidate:    [][]float64{{0, 0.005}},
+			reference:    EncodeCoords([][]float64{{0, 0}}),
+			bufferMeters: 600,
+			want:         true,
+		},
+	} {
+		got, err := WithinCorridor(EncodeCoords(tc.candidate), tc.reference, tc.bufferMeters)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+	_, err := WithinCorridor([]byte("_p~iF"), reference, 0)
+	assert.Equal(t, errUnterminatedSequence, err)
+	_, err = WithinCorridor(reference, []byte("_p~iF"), 0)
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestSharedLength(t *testing.T) {
+	b := EncodeCoords([][]float64{{0, 0}, {0, 1}})
+	for _, tc := range []struct {
+		a    [][]float64
+		want float64
+	}{
+		{
+			a:    [][]float64{{0, 0}, {0, 1}},
+			want: haversine([]float64{0, 0}, []float64{0, 1}),
+		},
+		{
+			a:    [][]float64{{0, 0}, {0, 0.5}, {1, 0.5}},
+			want: haversine([]float64{0, 0}, []float64{0, 0.5}),
+		},
+		{
+			a:    [][]float64{{1, 0}, {1, 1}},
+			want: 0,
+		},
+		{
+			a:    [][]float64{{0, 0}, {1, 0.5}, {0, 1}},
+			want: 0,
+		},
+		{
+			a:    [][]float64{{0, 0.5}},
+			want: 0,
+		},
+	} {
+		got, err := SharedLength(EncodeCoords(tc.a), b, 10)
+		assert.NoError(t, err)
+		assert.InDelta(t, tc.want, got, 1e-6)
+	}
+	_, err := SharedLength([]byte("_p~iF"), b, 0)
+	assert.Equal(t, errUnterminatedSequence, err)
+	_, err = SharedLength(b, []byte("_p~iF"), 0)
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeOffset(t *testing.T) {
+	m := 100 / (earthRadius * math.Pi / 180)
+	for _, tc := range []struct {
+		coords       [][]float64
+		offsetMeters float64
+		want         [][]float64
+	}{
+		{
+			coords:       [][]float64{{0, 0}, {0.01, 0}},
+			offsetMeters: 100,
+			want:         [][]float64{{0, m}, {0.01, m}},
+		},
+		{
+			coords:       [][]float64{{0, 0}, {0.01, 0}},
+			offsetMeters: -100,
+			want:         [][]float64{{0, -m}, {0.01, -m}},
+		},
+		{
+			coords:       [][]float64{{0, 0}, {0.01, 0}, {0.01, 0.01}},
+			offsetMeters: 100,
+			want:         [][]float64{{0, m}, {0.01 - m, m}, {0.01 - m, 0.01}},
+		},
+		{
+			coords:       [][]float64{{0, 0}, {0.01, 0}, {0.01, 0.01}},
+			offsetMeters: -100,
+			want:         [][]float64{{0, -m}, {0.01 + m, -m}, {0.01 + m, 0.01}},
+		},
+		{
+			coords:       [][]float64{{0, 0}, {0.01, 0}, {0, 0}},
+			offsetMeters: 100,
+			want:         [][]float64{{0, m}, {0.01, m}, {0.01, -m}, {0, -m}},
+		},
+		{
+			coords:       [][]float64{{0, 0}, {0, 0}, {0.01, 0}},
+			offsetMeters: 100,
+			want:         [][]float64{{0, m}, {0.01, m}},
+		},
+		{
+			coords:       [][]float64{{38.5, -120.2}, {38.5, -120.2}},
+			offsetMeters: 100,
+			want:         [][]float64{{38.5, -120.2}, {38.5, -120.2}},
+		},
+	} {
+		got, err := DecodeOffset(EncodeCoords(tc.coords), tc.offsetMeters)
+		assert.NoError(t, err)
+		if assert.Len(t, got, len(tc.want)) {
+			for i := range got {
+				assert.InDeltaSlice(t, tc.want[i], got[i], 1e-9)
+			}
+		}
+	}
+	_, err := DecodeOffset([]byte("_p~iF"), 100)
+	assert.Equal(t, errUnterminatedSequence, err)
+}
@@ -0,0 +1,41 @@
+// Package geojson converts polylines to GeoJSON.
+package geojson
+
+import (
+	"encoding/json"
+
+	"github.com/twpayne/go-polyline"
+)
+
+type lineString struct {
+	Type        string       `json:"type"`
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+type feature struct {
+	Type       string                 `json:"type"`
+	Geometry   lineString             `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// DecodeToFeature decodes an array of coordinates from buf using the default
+// codec and returns a GeoJSON Feature with a LineString geometry, with each
+// coordinate in (lng, lat) order, and properties props, and any error.
+func DecodeToFeature(buf []byte, props map[string]interface{}) (json.RawMessage, error) {
+	coords, _, err := polyline.DecodeCoords(buf)
+	if err != nil {
+		return nil, err
+	}
+	f := feature{
+		Type: "Feature",
+		Geometry: lineString{
+			Type:        "LineString",
+			Coordinates: make([][2]float64, len(coords)),
+		},
+		Properties: props,
+	}
+	for i, coord := range coords {
+		f.Geometry.Coordinates[i] = [2]float64{coord[1], coord[0]}
+	}
+	return json.Marshal(f)
+}
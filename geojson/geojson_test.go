@@ -0,0 +1,36 @@
+package geojson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeToFeature(t *testing.T) {
+	for _, tc := range []struct {
+		s     string
+		props map[string]interface{}
+		want  string
+	}{
+		{
+			s:     "_p~iF~ps|U_ulLnnqC",
+			props: map[string]interface{}{"name": "route", "id": 1},
+			want:  `{"type":"Feature","geometry":{"type":"LineString","coordinates":[[-120.2,38.5],[-120.95,40.7]]},"properties":{"id":1,"name":"route"}}`,
+		},
+		{
+			s:    "_p~iF~ps|U",
+			want: `{"type":"Feature","geometry":{"type":"LineString","coordinates":[[-120.2,38.5]]},"properties":null}`,
+		},
+	} {
+		got, err := DecodeToFeature([]byte(tc.s), tc.props)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, string(got))
+	}
+}
+
+func TestDecodeToFeatureErrors(t *testing.T) {
+	_, err := DecodeToFeature([]byte("_p~iF"), nil)
+	assert.Error(t, err)
+	_, err = DecodeToFeature([]byte("_p~iF~ps|U"), map[string]interface{}{"f": func() {}})
+	assert.Error(t, err)
+}
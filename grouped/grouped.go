@@ -0,0 +1,130 @@
+// Package grouped implements a non-standard, group-varint-style binary
+// encoding of polylines designed for fast decoding of very large polylines.
+//
+// The encoding is not compatible with the standard polyline format and is
+// intended only for storage and transport between programs that both use this
+// package. As in the standard format, coordinates are scaled by 1e5, delta
+// encoded, and zigzag encoded. The resulting unsigned integers are then
+// packed in groups of four, each group preceded by a control byte containing
+// a two-bit length code for each integer, so that the decoder can determine
+// the layout of the whole group from a single byte rather than testing a
+// continuation bit in every byte.
+package grouped
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	dim       = 2
+	scale     = 1e5
+	groupSize = 4
+)
+
+var (
+	errInvalidHeader = errors.New("invalid header")
+	errTrailingData  = errors.New("trailing data")
+	errTruncated     = errors.New("truncated data")
+)
+
+// lengths maps a length code to the number of bytes of an integer.
+var lengths = [4]int{1, 2, 4, 8}
+
+// masks maps a length code to a mask of the bytes of an integer.
+var masks = [4]uint64{0xff, 0xffff, 0xffffffff, math.MaxUint64}
+
+// lengthCode returns the length code of the smallest length that can hold u.
+func lengthCode(u uint64) byte {
+	switch {
+	case u <= 0xff:
+		return 0
+	case u <= 0xffff:
+		return 1
+	case u <= 0xffffffff:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// EncodeCoordsGrouped returns the grouped encoding of an array of
+// two-dimensional coordinates. The result consists of the number of
+// coordinates as a uvarint followed by the groups of integers.
+func EncodeCoordsGrouped(coords [][]float64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+dim*len(coords)*9/groupSize+dim*len(coords))
+	buf = buf[:binary.PutUvarint(buf, uint64(len(coords)))]
+	values := make([]uint64, 0, dim*len(coords))
+	var last [dim]int64
+	for _, coord := range coords {
+		for j := range last {
+			x := int64(math.Round(scale * coord[j]))
+			d := x - last[j]
+			values = append(values, uint64(d<<1^d>>63))
+			last[j] = x
+		}
+	}
+	var b [8]byte
+	for i := 0; i < len(values); i += groupSize {
+		control := len(buf)
+		buf = append(buf, 0)
+		for j := 0; j < groupSize && i+j < len(values); j++ {
+			code := lengthCode(values[i+j])
+			buf[control] |= code << (2 * uint(j))
+			binary.LittleEndian.PutUint64(b[:], values[i+j])
+			buf = append(buf, b[:lengths[code]]...)
+		}
+	}
+	return buf
+}
+
+// DecodeCoordsGrouped decodes an array of two-dimensional coordinates encoded
+// with EncodeCoordsGrouped from buf. It returns the coordinates and any error.
+func DecodeCoordsGrouped(buf []byte) ([][]float64, error) {
+	n, k := binary.Uvarint(buf)
+	if k <= 0 {
+		return nil, errInvalidHeader
+	}
+	buf = buf[k:]
+	if n > uint64(len(buf)/dim) {
+		return nil, errTruncated
+	}
+	m := dim * int(n)
+	flat := make([]float64, m)
+	var last [dim]int64
+	for i := 0; i < m; i += groupSize {
+		if len(buf) == 0 {
+			return nil, errTruncated
+		}
+		control := buf[0]
+		buf = buf[1:]
+		for j := 0; j < groupSize && i+j < m; j++ {
+			code := control >> (2 * uint(j)) & 3
+			l := lengths[code]
+			var u uint64
+			switch {
+			case len(buf) >= 8:
+				u = binary.LittleEndian.Uint64(buf) & masks[code]
+			case len(buf) >= l:
+				for k := l - 1; k >= 0; k-- {
+					u = u<<8 | uint64(buf[k])
+				}
+			default:
+				return nil, errTruncated
+			}
+			buf = buf[l:]
+			x := &last[(i+j)%dim]
+			*x += int64(u>>1) ^ -int64(u&1)
+			flat[i+j] = float64(*x) / scale
+		}
+	}
+	if len(buf) != 0 {
+		return nil, errTrailingData
+	}
+	coords := make([][]float64, n)
+	for i := range coords {
+		coords[i] = flat[dim*i : dim*(i+1) : dim*(i+1)]
+	}
+	return coords, nil
+}
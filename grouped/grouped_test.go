@@ -0,0 +1,101 @@
+package grouped
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/twpayne/go-polyline"
+)
+
+func TestGrouped(t *testing.T) {
+	for _, tc := range []struct {
+		cs  [][]float64
+		hex string
+	}{
+		{
+			cs:  [][]float64{},
+			hex: "00",
+		},
+		{
+			cs:  [][]float64{{38.5, -120.2}},
+			hex: "010a207e75003fd26e01",
+		},
+		{
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			cs: [][]float64{{0, 0}, {0.00001, -0.00001}, {-900, 1800}},
+		},
+	} {
+		buf := EncodeCoordsGrouped(tc.cs)
+		if tc.hex != "" {
+			assert.Equal(t, tc.hex, hex.EncodeToString(buf))
+		}
+		got, err := DecodeCoordsGrouped(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.cs, got)
+	}
+}
+
+func TestGroupedMatchesStandard(t *testing.T) {
+	coords := benchmarkCoords(1000)
+	got, err := DecodeCoordsGrouped(EncodeCoordsGrouped(coords))
+	assert.NoError(t, err)
+	assert.Equal(t, string(polyline.EncodeCoords(coords)), string(polyline.EncodeCoords(got)))
+}
+
+func TestDecodeCoordsGroupedErrors(t *testing.T) {
+	for _, tc := range []struct {
+		hex string
+		err error
+	}{
+		{hex: "", err: errInvalidHeader},
+		{hex: "80", err: errInvalidHeader},
+		{hex: "02", err: errTruncated},
+		{hex: "01050102", err: errTruncated},
+		{hex: "010a207e75003fd26e", err: errTruncated},
+		{hex: "01010a207e75003fd26e0100", err: errTrailingData},
+	} {
+		buf, err := hex.DecodeString(tc.hex)
+		assert.NoError(t, err)
+		_, err = DecodeCoordsGrouped(buf)
+		assert.Equal(t, tc.err, err)
+	}
+}
+
+func benchmarkCoords(n int) [][]float64 {
+	r := rand.New(rand.NewSource(1))
+	coords := make([][]float64, n)
+	lat, lng := 45.0, 5.0
+	for i := range coords {
+		lat += 0.001 * (r.Float64() - 0.5)
+		lng += 0.001 * (r.Float64() - 0.5)
+		coords[i] = []float64{lat, lng}
+	}
+	return coords
+}
+
+func BenchmarkDecodeCoordsGrouped(b *testing.B) {
+	buf := EncodeCoordsGrouped(benchmarkCoords(100000))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeCoordsGrouped(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeCoordsStandard(b *testing.B) {
+	buf := polyline.EncodeCoords(benchmarkCoords(100000))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := polyline.DecodeCoords(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
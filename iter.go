@@ -0,0 +1,63 @@
+//go:build go1.23
+// +build go1.23
+
+package polyline
+
+import "iter"
+
+// Coords returns an iterator over the coordinates encoded in buf. If a
+// coordinate cannot be decoded then the iterator yields a nil coordinate with
+// the error and stops.
+func (c Codec) Coords(buf []byte) iter.Seq2[[]float64, error] {
+	return func(yield func([]float64, error) bool) {
+		if err := c.validate(); err != nil {
+			yield(nil, err)
+			return
+		}
+		last := make([]int, c.Dim)
+		for len(buf) > 0 {
+			coord := make([]float64, c.Dim)
+			var err error
+			if buf, err = c.decodeCoord(buf, last, coord); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(coord, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Coords returns an iterator over the coordinates encoded in buf using the
+// default codec.
+func Coords(buf []byte) iter.Seq2[[]float64, error] {
+	return defaultCodec.Coords(buf)
+}
+
+// CoordsWithOffset returns an iterator over the coordinates encoded in buf that
+// yields the byte offset in buf at which each coordinate's encoding starts and
+// the coordinate itself. Iteration stops at the first coordinate that cannot
+// be decoded, after yielding its offset with a nil coordinate, so callers can
+// detect errors by checking for a nil coordinate.
+func (c Codec) CoordsWithOffset(buf []byte) iter.Seq2[int, []float64] {
+	return func(yield func(int, []float64) bool) {
+		if c.validate() != nil {
+			yield(0, nil)
+			return
+		}
+		last := make([]int, c.Dim)
+		for offset := 0; offset < len(buf); {
+			coord := make([]float64, c.Dim)
+			rest, err := c.decodeCoord(buf[offset:], last, coord)
+			if err != nil {
+				yield(offset, nil)
+				return
+			}
+			if !yield(offset, coord) {
+				return
+			}
+			offset = len(buf) - len(rest)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+//go:build go1.23
+// +build go1.23
+
+package polyline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoordsIter(t *testing.T) {
+	for _, tc := range []struct {
+		s      string
+		coords [][]float64
+		err    error
+	}{
+		{
+			s: "",
+		},
+		{
+			s:      "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			coords: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			s:      "_p~iF~ps|U_ulL",
+			coords: [][]float64{{38.5, -120.2}, nil},
+			err:    errUnterminatedSequence,
+		},
+		{
+			s:      "_p~iF~ps|U!",
+			coords: [][]float64{{38.5, -120.2}, nil},
+			err:    errInvalidByte,
+		},
+	} {
+		var coords [][]float64
+		var err error
+		for coord, e := range Coords([]byte(tc.s)) {
+			coords = append(coords, coord)
+			err = e
+		}
+		assert.Equal(t, tc.coords, coords)
+		assert.Equal(t, tc.err, err)
+	}
+}
+
+func TestCoordsIterCodec(t *testing.T) {
+	for _, tc := range []struct {
+		c    Codec
+		want [][]float64
+	}{
+		{
+			c:    Codec{Dim: 3, Scale: 1, ChunkBits: 4},
+			want: [][]float64{{1, 2, 3}, {-100, 200, -300}},
+		},
+		{
+			c:    Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{0, 0, 1e2}},
+			want: [][]float64{{38.5, -120.2, 100.25}, {40.7, -120.95, 99.5}},
+		},
+	} {
+		var got [][]float64
+		for coord, err := range tc.c.Coords(tc.c.EncodeCoords(nil, tc.want)) {
+			assert.NoError(t, err)
+			got = append(got, coord)
+		}
+		assert.Equal(t, tc.want, got)
+		got = nil
+		for _, coord := range tc.c.CoordsWithOffset(tc.c.EncodeCoords(nil, tc.want)) {
+			got = append(got, coord)
+		}
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestCoordsIterInvalidCodec(t *testing.T) {
+	c := Codec{Dim: 2, Scale: 1e5, Scales: [MaxScales]float64{1e5, 1e5, 1e2}}
+	for coord, err := range c.Coords([]byte("_p~iF~ps|U")) {
+		assert.Nil(t, coord)
+		assert.Equal(t, errDimensionalMismatch, err)
+	}
+	for offset, coord := range c.CoordsWithOffset([]byte("_p~iF~ps|U")) {
+		assert.Equal(t, 0, offset)
+		assert.Nil(t, coord)
+	}
+}
+
+func TestCoordsWithOffset(t *testing.T) {
+	for _, tc := range []struct {
+		s       string
+		offsets []int
+		coords  [][]float64
+	}{
+		{
+			s: "",
+		},
+		{
+			s:       "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			offsets: []int{0, 10, 18},
+			coords:  [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			s:       "_p~iF~ps|U_ulL",
+			offsets: []int{0, 10},
+			coords:  [][]float64{{38.5, -120.2}, nil},
+		},
+		{
+			s:       "_p~iF~ps|U!",
+			offsets: []int{0, 10},
+			coords:  [][]float64{{38.5, -120.2}, nil},
+		},
+	} {
+		var offsets []int
+		var coords [][]float64
+		for offset, coord := range defaultCodec.CoordsWithOffset([]byte(tc.s)) {
+			offsets = append(offsets, offset)
+			coords = append(coords, coord)
+		}
+		assert.Equal(t, tc.offsets, offsets)
+		assert.Equal(t, tc.coords, coords)
+	}
+}
+
+func TestCoordsWithOffsetBreak(t *testing.T) {
+	n := 0
+	for offset, coord := range defaultCodec.CoordsWithOffset([]byte("_p~iF~ps|U_ulLnnqC_mqNvxq`@")) {
+		assert.Equal(t, 0, offset)
+		assert.Equal(t, []float64{38.5, -120.2}, coord)
+		n++
+		break
+	}
+	assert.Equal(t, 1, n)
+}
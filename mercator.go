@@ -0,0 +1,86 @@
+package polyline
+
+import "math"
+
+// maxMercatorLat is the greatest latitude representable in Web Mercator,
+// atan(sinh(π)) in degrees.
+const maxMercatorLat = 85.0511287798066
+
+// tileSize is the size of a Web Mercator tile in pixels.
+const tileSize = 256
+
+// maxZoom is the greatest zoom level supported by DecodeToPixels, chosen so
+// that pixel coordinates fit in an int32.
+const maxZoom = 22
+
+// mercator projects lat and lng to Web Mercator, returning x and y in the
+// range [0, 1] with the origin at the top left. Latitudes beyond the Web
+// Mercator limits are clamped.
+func mercator(lat, lng float64) (float64, float64) {
+	lat = math.Max(-maxMercatorLat, math.Min(maxMercatorLat, lat))
+	x := (lng + 180) / 360
+	y := (1 - math.Log(math.Tan(radians(lat))+1/math.Cos(radians(lat)))/math.Pi) / 2
+	return x, math.Max(0, math.Min(1, y))
+}
+
+// decodeMercator decodes an array of coordinates from buf using the default
+// codec and calls fn with each coordinate projected to Web Mercator. It returns
+// any error.
+func decodeMercator(buf []byte, fn func(x, y float64)) error {
+	var last [2]int
+	for first := true; first || len(buf) > 0; first = false {
+		var err error
+		if buf, err = defaultCodec.decodeInts(buf, last[:]); err != nil {
+			return err
+		}
+		fn(mercator(float64(last[0])/defaultCodec.Scale, float64(last[1])/defaultCodec.Scale))
+	}
+	return nil
+}
+
+// DecodeToPixels decodes an array of coordinates from buf using the default
+// codec and projects them to global Web Mercator pixel coordinates at zoom,
+// with 256 pixel tiles and the origin at the top left, so that x and y range
+// from 0 to (256<<zoom)-1. Latitudes beyond the Web Mercator limit of
+// ±85.0511° are clamped. zoom must be between 0 and 22 inclusive. It returns
+// the pixel coordinates and any error.
+func DecodeToPixels(buf []byte, zoom int) ([][2]int, error) {
+	if zoom < 0 || zoom > maxZoom {
+		return nil, errInvalidZoom
+	}
+	size := tileSize << uint(zoom)
+	toPixel := func(t float64) int {
+		p := int(math.Floor(t * float64(size)))
+		if p >= size {
+			return size - 1
+		}
+		return p
+	}
+	var pixels [][2]int
+	if err := decodeMercator(buf, func(x, y float64) {
+		pixels = append(pixels, [2]int{toPixel(x), toPixel(y)})
+	}); err != nil {
+		return nil, err
+	}
+	return pixels, nil
+}
+
+// DecodeTileLocal decodes an array of coordinates from buf using the default
+// codec, projects them to Web Mercator, and returns them in the local
+// coordinates of tile z/x/y, where the tile's top left is (0, 0) and its bottom
+// right is (extent, extent), and any error. Coordinates outside the tile are
+// not clipped, so their local coordinates may be negative or greater than
+// extent.
+func DecodeTileLocal(buf []byte, z, x, y, extent int) ([][2]int, error) {
+	tiles := float64(int(1) << uint(z))
+	var local [][2]int
+	if err := decodeMercator(buf, func(mx, my float64) {
+		local = append(local, [2]int{
+			round((mx*tiles - float64(x)) * float64(extent)),
+			round((my*tiles - float64(y)) * float64(extent)),
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return local, nil
+}
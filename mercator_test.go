@@ -0,0 +1,87 @@
+package polyline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeToPixels(t *testing.T) {
+	for _, tc := range []struct {
+		coords [][]float64
+		zoom   int
+		want   [][2]int
+	}{
+		{
+			coords: [][]float64{{0, 0}},
+			zoom:   0,
+			want:   [][2]int{{128, 128}},
+		},
+		{
+			coords: [][]float64{{0, 0}, {85.05113, -180}, {-85.05113, 180}},
+			zoom:   1,
+			want:   [][2]int{{256, 256}, {0, 0}, {511, 511}},
+		},
+		{
+			coords: [][]float64{{89, 0}, {-89, 0}},
+			zoom:   0,
+			want:   [][2]int{{128, 0}, {128, 255}},
+		},
+		{
+			coords: [][]float64{{51.5, -0.12}},
+			zoom:   10,
+			want:   [][2]int{{130984, 87178}},
+		},
+	} {
+		got, err := DecodeToPixels(EncodeCoords(tc.coords), tc.zoom)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+	_, err := DecodeToPixels([]byte("_p~iF"), 0)
+	assert.Equal(t, errUnterminatedSequence, err)
+	for _, zoom := range []int{-1, maxZoom + 1, 64} {
+		_, err := DecodeToPixels([]byte("??"), zoom)
+		assert.Equal(t, errInvalidZoom, err)
+	}
+	got, err := DecodeToPixels([]byte("??"), maxZoom)
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]int{{1 << 29, 1 << 29}}, got)
+}
+
+func TestDecodeTileLocal(t *testing.T) {
+	for _, tc := range []struct {
+		coords  [][]float64
+		z, x, y int
+		extent  int
+		want    [][2]int
+	}{
+		{
+			coords: [][]float64{{0, 0}, {85.05113, -180}},
+			z:      0,
+			extent: 4096,
+			want:   [][2]int{{2048, 2048}, {0, 0}},
+		},
+		{
+			coords: [][]float64{{0, 0}, {0, 90}, {-85.05113, 180}},
+			z:      1,
+			x:      1,
+			y:      1,
+			extent: 4096,
+			want:   [][2]int{{0, 0}, {2048, 0}, {4096, 4096}},
+		},
+		{
+			coords: [][]float64{{0, -90}, {85.05113, -180}},
+			z:      1,
+			x:      1,
+			y:      1,
+			extent: 4096,
+			want:   [][2]int{{-2048, 0}, {-4096, -4096}},
+		},
+	} {
+		got, err := DecodeTileLocal(EncodeCoords(tc.coords), tc.z, tc.x, tc.y, tc.extent)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+	_, err := DecodeTileLocal([]byte("_p~iF"), 0, 0, 0, 4096)
+	assert.Equal(t, errUnterminatedSequence, err)
+}
@@ -9,19 +9,44 @@
 // slice as input (which can be nil) and return a new byte slice with the
 // encoded value appended to it, similarly to how Go's append function works. To
 // increase performance, you can pre-allocate byte slices, for example by
-// passing make([]byte, 0, 128) as the input byte slice. Similarly, decoding
-// functions take a byte slice as input and return the remaining unconsumed
-// bytes as output.
+// passing make([]byte, 0, 128) as the input byte slice, or
+// make([]byte, 0, c.EncodedLen(coords)) to allocate exactly the required
+// capacity. Similarly, decoding functions take a byte slice as input and return
+// the remaining unconsumed bytes as output.
 package polyline
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"hash/crc32"
 	"math"
+	"math/bits"
+	"runtime"
+	"sync"
 )
 
 var (
+	errBufferTooSmall       = errors.New("buffer too small")
+	errChecksumMismatch     = errors.New("checksum mismatch")
+	errCorpusMismatch       = errors.New("corpus mismatch")
+	errCountMismatch        = errors.New("coordinate count mismatch")
 	errDimensionalMismatch  = errors.New("dimensional mismatch")
+	errIndexOutOfRange      = errors.New("index out of range")
+	errInvalidAccuracy      = errors.New("invalid accuracy")
 	errInvalidByte          = errors.New("invalid byte")
+	errInvalidCapacity      = errors.New("invalid capacity")
+	errInvalidChunkBits     = errors.New("invalid chunk bits")
+	errInvalidPermutation   = errors.New("invalid permutation")
+	errInvalidSamples       = errors.New("invalid number of samples")
+	errInvalidTag           = errors.New("invalid tag")
+	errInvalidWindow        = errors.New("invalid window")
+	errInvalidZoom          = errors.New("invalid zoom")
+	errLengthMismatch       = errors.New("length mismatch")
+	errNonASCII             = errors.New("non-ASCII byte")
+	errOverflow             = errors.New("overflow")
+	errPanic                = errors.New("panic")
+	errTooFewCoords         = errors.New("too few coordinates")
 	errUnterminatedSequence = errors.New("unterminated sequence")
 )
 
@@ -32,14 +57,241 @@ func round(x float64) int {
 	return int(math.Floor(x + 0.5))
 }
 
-// A Codec represents an encoder.
+// A CoordSystem identifies the coordinate system of encoded coordinates.
+type CoordSystem int
+
+// Coordinate systems.
+const (
+	Geographic CoordSystem = iota // Latitude and longitude
+	Projected                     // Planar coordinates, for example UTM
+)
+
+// A Codec represents an encoder. A Codec whose ChunkBits is out of range, or
+// whose Scales has a non-zero entry at or beyond Dim, is invalid: methods that
+// return an error return errInvalidChunkBits or errDimensionalMismatch
+// respectively, Valid returns false, and other methods panic. The exceptions
+// are IsStandard, which returns false, and DecodeUint, DecodeInt, EncodeUint,
+// and EncodeInt, which encode single integers and so only check ChunkBits.
 type Codec struct {
-	Dim   int     // Dimensionality, normally 2
-	Scale float64 // Scale, normally 1e5
+	Dim         int         // Dimensionality, normally 2
+	Scale       float64     // Scale, normally 1e5
+	Radians     bool        // Coordinates are in radians rather than degrees
+	ChunkBits   int         // Bits per encoded byte, 1 to 6, or 0 for the standard 5
+	CoordSystem CoordSystem // Coordinate system, normally Geographic
+
+	// Scales overrides Scale with a separate scale for each of the first
+	// MaxScales dimensions, for example to scale altitude differently from
+	// latitude and longitude. A zero entry means that the dimension uses Scale.
+	// Entries at or beyond Dim must be zero.
+	Scales [MaxScales]float64
 }
 
+// MaxScales is the number of dimensions that can have their own scale.
+const MaxScales = 4
+
 var defaultCodec = Codec{Dim: 2, Scale: 1e5}
 
+// Common precisions.
+const (
+	Polyline5 = 5 // Standard Google polylines
+	Polyline6 = 6 // Higher-precision polylines, as used by OSRM and Valhalla
+)
+
+// NewCodec returns a new Codec for dim-dimensional coordinates with precision
+// decimal digits, i.e. with a scale of 10 to the power of precision. It panics
+// if precision is negative.
+func NewCodec(dim, precision int) Codec {
+	if precision < 0 {
+		panic("polyline: invalid precision")
+	}
+	return Codec{Dim: dim, Scale: math.Pow10(precision)}
+}
+
+// isDefault returns whether c is equivalent to the default codec, in which
+// case the specialized implementations for two-dimensional coordinates scaled
+// by 1e5 can be used.
+func (c Codec) isDefault() bool {
+	return c.Dim == 2 && c.Scale == 1e5 && c.Scales == [MaxScales]float64{} && !c.Radians && c.standardChunkBits()
+}
+
+// chunkBits returns the number of bits per encoded byte.
+func (c Codec) chunkBits() uint {
+	switch {
+	case c.ChunkBits == 0:
+		return 5
+	case 1 <= c.ChunkBits && c.ChunkBits <= 6:
+		return uint(c.ChunkBits)
+	default:
+		panic("polyline: invalid chunk bits")
+	}
+}
+
+// standardChunkBits returns whether c uses the standard 5 bits per encoded
+// byte. Unlike chunkBits it does not panic if c.ChunkBits is invalid.
+func (c Codec) standardChunkBits() bool {
+	return c.ChunkBits == 0 || c.ChunkBits == 5
+}
+
+// checkChunkBits returns errInvalidChunkBits if c.ChunkBits is out of range.
+func (c Codec) checkChunkBits() error {
+	if c.ChunkBits < 0 || c.ChunkBits > 6 {
+		return errInvalidChunkBits
+	}
+	return nil
+}
+
+// terminatorLimit returns the first byte value that is not the final byte of
+// an encoded integer. Continuation bytes start at this value.
+func (c Codec) terminatorLimit() byte {
+	return 63 + 1<<c.chunkBits()
+}
+
+// validate returns errInvalidChunkBits if c.ChunkBits is out of range and
+// errDimensionalMismatch if c.Scales has a non-zero entry at or beyond c.Dim.
+func (c Codec) validate() error {
+	if err := c.checkChunkBits(); err != nil {
+		return err
+	}
+	for i, scale := range c.Scales {
+		if i >= c.Dim && scale != 0 {
+			return errDimensionalMismatch
+		}
+	}
+	return nil
+}
+
+// mustValidate panics if c is invalid. It is used by encoding methods that
+// cannot return an error.
+func (c Codec) mustValidate() {
+	if err := c.validate(); err != nil {
+		panic("polyline: " + err.Error())
+	}
+}
+
+// scale returns the scale of dimension i.
+func (c Codec) scale(i int) float64 {
+	if i < MaxScales && c.Scales[i] != 0 {
+		return c.Scales[i]
+	}
+	return c.Scale
+}
+
+// toInt converts x, a value in dimension i, to scaled integer units.
+func (c Codec) toInt(i int, x float64) int {
+	if c.Radians {
+		x = degrees(x)
+	}
+	return round(c.scale(i) * x)
+}
+
+// toFloat converts k, a value in dimension i, from scaled integer units.
+func (c Codec) toFloat(i, k int) float64 {
+	x := float64(k) / c.scale(i)
+	if c.Radians {
+		x = radians(x)
+	}
+	return x
+}
+
+// decodeInts decodes len(last) integer deltas from buf and adds them to last.
+// It returns the remaining unconsumed bytes of buf and any error.
+func (c Codec) decodeInts(buf []byte, last []int) ([]byte, error) {
+	for j := range last {
+		k, rest, err := c.DecodeInt(buf)
+		if err != nil {
+			return nil, err
+		}
+		last[j] += k
+		buf = rest
+	}
+	return buf, nil
+}
+
+// decodeCoord decodes a single coordinate's deltas from buf, adds them to last,
+// and stores the resulting coordinate in coord. last and coord must have length
+// c.Dim. It returns the remaining unconsumed bytes of buf and any error.
+func (c Codec) decodeCoord(buf []byte, last []int, coord []float64) ([]byte, error) {
+	buf, err := c.decodeInts(buf, last)
+	if err != nil {
+		return nil, err
+	}
+	for j, x := range last {
+		coord[j] = c.toFloat(j, x)
+	}
+	return buf, nil
+}
+
+// decodeCoordsDefault is the implementation of DecodeCoords specialized for
+// the default codec. It counts the coordinates first so that the coordinate
+// values can be allocated in a single backing array.
+func decodeCoordsDefault(buf []byte) ([][]float64, []byte, error) {
+	n, err := countInts(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n == 0 || n%2 != 0 {
+		return nil, nil, errUnterminatedSequence
+	}
+	flatCoords := make([]float64, n)
+	coords := make([][]float64, n/2)
+	var lat, lng float64
+	for i := range coords {
+		var dLat, dLng int
+		dLat, buf, err = DecodeInt(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+		dLng, buf, err = DecodeInt(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+		lat += float64(dLat) / 1e5
+		lng += float64(dLng) / 1e5
+		coord := flatCoords[2*i : 2*i+2 : 2*i+2]
+		coord[0], coord[1] = lat, lng
+		coords[i] = coord
+	}
+	return coords, nil, nil
+}
+
+// encodeCoordsDefault is the implementation of EncodeCoords specialized for
+// the default codec.
+func encodeCoordsDefault(buf []byte, coords [][]float64) []byte {
+	var lastLat, lastLng int
+	for _, coord := range coords {
+		lat, lng := round(1e5*coord[0]), round(1e5*coord[1])
+		buf = EncodeInt(buf, lat-lastLat)
+		buf = EncodeInt(buf, lng-lastLng)
+		lastLat, lastLng = lat, lng
+	}
+	return buf
+}
+
+// precision returns the base ten logarithm of c.Scale and whether c.Scale is
+// a non-negative integer power of ten.
+func (c Codec) precision() (int, bool) {
+	return scalePrecision(c.Scale)
+}
+
+// scalePrecision returns the base ten logarithm of scale and whether scale is a
+// non-negative integer power of ten.
+func scalePrecision(scale float64) (int, bool) {
+	precision := int(math.Round(math.Log10(scale)))
+	return precision, precision >= 0 && math.Pow10(precision) == scale
+}
+
+// IsStandard returns whether c is a commonly-used codec, i.e. c.Scale is a
+// non-negative integer power of ten, c.Dim is 2 or 3, c uses the standard 5
+// chunk bits, and c does not use per-dimension Scales. Codecs with other
+// scales, for example 99999 instead of 1e5, are almost always configuration
+// errors that silently produce polylines that other implementations decode to
+// subtly wrong coordinates. IsStandard does not restrict which codecs can be
+// used; it is intended for assertions in tests and at configuration time.
+func (c Codec) IsStandard() bool {
+	_, ok := c.precision()
+	return ok && (c.Dim == 2 || c.Dim == 3) && c.standardChunkBits() && c.Scales == [MaxScales]float64{}
+}
+
 // DecodeUint decodes a single unsigned integer from buf. It returns the decoded
 // uint, the remaining unconsumed bytes of buf, and any error.
 func DecodeUint(buf []byte) (uint, []byte, error) {
@@ -47,10 +299,18 @@ func DecodeUint(buf []byte) (uint, []byte, error) {
 	for i, b := range buf {
 		switch {
 		case 63 <= b && b < 95:
-			u += (uint(b) - 63) << shift
+			v := uint(b) - 63
+			if v<<shift>>shift != v {
+				return 0, nil, errOverflow
+			}
+			u += v << shift
 			return u, buf[i+1:], nil
 		case 95 <= b && b < 127:
-			u += (uint(b) - 95) << shift
+			v := uint(b) - 95
+			if shift >= bits.UintSize || v<<shift>>shift != v {
+				return 0, nil, errOverflow
+			}
+			u += v << shift
 			shift += 5
 		default:
 			return 0, nil, errInvalidByte
@@ -69,12 +329,95 @@ func DecodeInt(buf []byte) (int, []byte, error) {
 	if u&1 == 0 {
 		return int(u >> 1), buf, nil
 	}
-	return -int((u + 1) >> 1), buf, nil
+	return ^int(u >> 1), buf, nil
+}
+
+// DecodeUint64 decodes a single unsigned 64-bit integer from buf. It returns
+// the decoded uint64, the remaining unconsumed bytes of buf, and any error.
+func DecodeUint64(buf []byte) (uint64, []byte, error) {
+	var u uint64
+	var shift uint
+	for i, b := range buf {
+		switch {
+		case 63 <= b && b < 95:
+			v := uint64(b) - 63
+			if v<<shift>>shift != v {
+				return 0, nil, errOverflow
+			}
+			u += v << shift
+			return u, buf[i+1:], nil
+		case 95 <= b && b < 127:
+			v := uint64(b) - 95
+			if shift >= 64 || v<<shift>>shift != v {
+				return 0, nil, errOverflow
+			}
+			u += v << shift
+			shift += 5
+		default:
+			return 0, nil, errInvalidByte
+		}
+	}
+	return 0, nil, errUnterminatedSequence
+}
+
+// DecodeInt64 decodes a single signed 64-bit integer from buf. It returns the
+// decoded int64, the remaining unconsumed bytes of buf, and any error.
+func DecodeInt64(buf []byte) (int64, []byte, error) {
+	u, buf, err := DecodeUint64(buf)
+	if err != nil {
+		return 0, nil, err
+	}
+	if u&1 == 0 {
+		return int64(u >> 1), buf, nil
+	}
+	return ^int64(u >> 1), buf, nil
+}
+
+// MaxEncodedLen is the maximum length of the encoding of a single integer.
+const MaxEncodedLen = 13
+
+// PutUint writes the encoding of a single unsigned integer u to the start of
+// dst and returns the number of bytes written.
+func PutUint(dst *[MaxEncodedLen]byte, u uint) int {
+	n := 0
+	for u >= 32 {
+		dst[n] = byte((u & 31) + 95)
+		n++
+		u >>= 5
+	}
+	dst[n] = byte(u + 63)
+	return n + 1
+}
+
+// PutInt writes the encoding of a single signed integer i to the start of dst
+// and returns the number of bytes written.
+func PutInt(dst *[MaxEncodedLen]byte, i int) int {
+	u := uint(i) << 1
+	if i < 0 {
+		u = ^u
+	}
+	return PutUint(dst, u)
 }
 
 // EncodeUint appends the encoding of a single unsigned integer u to buf and
 // returns the new buf.
 func EncodeUint(buf []byte, u uint) []byte {
+	var dst [MaxEncodedLen]byte
+	n := PutUint(&dst, u)
+	return append(buf, dst[:n]...)
+}
+
+// EncodeInt appends the encoding of a single signed integer i to buf and
+// returns the new buf.
+func EncodeInt(buf []byte, i int) []byte {
+	var dst [MaxEncodedLen]byte
+	n := PutInt(&dst, i)
+	return append(buf, dst[:n]...)
+}
+
+// EncodeUint64 appends the encoding of a single unsigned 64-bit integer u to
+// buf and returns the new buf.
+func EncodeUint64(buf []byte, u uint64) []byte {
 	for u >= 32 {
 		buf = append(buf, byte((u&31)+95))
 		u >>= 5
@@ -83,44 +426,348 @@ func EncodeUint(buf []byte, u uint) []byte {
 	return buf
 }
 
-// EncodeInt appends the encoding of a single signed integer i to buf and
-// returns the new buf.
-func EncodeInt(buf []byte, i int) []byte {
-	var u uint
+// EncodeInt64 appends the encoding of a single signed 64-bit integer i to buf
+// and returns the new buf.
+func EncodeInt64(buf []byte, i int64) []byte {
+	u := uint64(i) << 1
+	if i < 0 {
+		u = ^u
+	}
+	return EncodeUint64(buf, u)
+}
+
+// DecodeUint decodes a single unsigned integer from buf using c's chunk bits.
+// It returns the decoded uint, the remaining unconsumed bytes of buf, and any
+// error.
+func (c Codec) DecodeUint(buf []byte) (uint, []byte, error) {
+	if err := c.checkChunkBits(); err != nil {
+		return 0, nil, err
+	}
+	k := c.chunkBits()
+	if k == 5 {
+		return DecodeUint(buf)
+	}
+	limit := c.terminatorLimit()
+	var u, shift uint
+	for i, b := range buf {
+		switch {
+		case 63 <= b && b < limit:
+			v := uint(b) - 63
+			if v<<shift>>shift != v {
+				return 0, nil, errOverflow
+			}
+			u += v << shift
+			return u, buf[i+1:], nil
+		case limit <= b && b < 2*limit-63:
+			v := uint(b - limit)
+			if shift >= bits.UintSize || v<<shift>>shift != v {
+				return 0, nil, errOverflow
+			}
+			u += v << shift
+			shift += k
+		default:
+			return 0, nil, errInvalidByte
+		}
+	}
+	return 0, nil, errUnterminatedSequence
+}
+
+// DecodeInt decodes a single signed integer from buf using c's chunk bits. It
+// returns the decoded int, the remaining unconsumed bytes of buf, and any
+// error.
+func (c Codec) DecodeInt(buf []byte) (int, []byte, error) {
+	u, buf, err := c.DecodeUint(buf)
+	if err != nil {
+		return 0, nil, err
+	}
+	if u&1 == 0 {
+		return int(u >> 1), buf, nil
+	}
+	return ^int(u >> 1), buf, nil
+}
+
+// EncodeUint appends the encoding of a single unsigned integer u using c's
+// chunk bits to buf and returns the new buf.
+func (c Codec) EncodeUint(buf []byte, u uint) []byte {
+	k := c.chunkBits()
+	if k == 5 {
+		return EncodeUint(buf, u)
+	}
+	limit := c.terminatorLimit()
+	mask := uint(1)<<k - 1
+	for u > mask {
+		buf = append(buf, byte(u&mask)+limit)
+		u >>= k
+	}
+	return append(buf, byte(u)+63)
+}
+
+// EncodeInt appends the encoding of a single signed integer i using c's chunk
+// bits to buf and returns the new buf.
+func (c Codec) EncodeInt(buf []byte, i int) []byte {
+	u := uint(i) << 1
 	if i < 0 {
-		u = uint(^(i << 1))
-	} else {
-		u = uint(i << 1)
+		u = ^u
+	}
+	return c.EncodeUint(buf, u)
+}
+
+// CountCoords returns the number of coordinates encoded in buf without
+// decoding them, and any error. It returns errDimensionalMismatch if the number
+// of encoded integers is not a multiple of c.Dim.
+func (c Codec) CountCoords(buf []byte) (int, error) {
+	if err := c.validate(); err != nil {
+		return 0, err
+	}
+	n, err := c.countInts(buf)
+	if err != nil {
+		return 0, err
+	}
+	if n%c.Dim != 0 {
+		return 0, errDimensionalMismatch
+	}
+	return n / c.Dim, nil
+}
+
+// Valid returns whether buf contains a valid encoding of an array of
+// coordinates, that is whether DecodeCoords would decode it without error. An
+// empty buf is not valid. Valid does not allocate.
+func (c Codec) Valid(buf []byte) bool {
+	if len(buf) == 0 || c.validate() != nil {
+		return false
+	}
+	n := 0
+	for len(buf) > 0 {
+		var err error
+		if _, buf, err = c.DecodeUint(buf); err != nil {
+			return false
+		}
+		n++
+	}
+	return n%c.Dim == 0
+}
+
+// countInts returns the number of integers encoded in buf using c's chunk bits
+// and any error.
+func (c Codec) countInts(buf []byte) (int, error) {
+	if err := c.checkChunkBits(); err != nil {
+		return 0, err
+	}
+	if c.chunkBits() == 5 {
+		return countInts(buf)
+	}
+	limit := c.terminatorLimit()
+	n := 0
+	for _, b := range buf {
+		switch {
+		case 63 <= b && b < limit:
+			n++
+		case limit <= b && b < 2*limit-63:
+		default:
+			return 0, errInvalidByte
+		}
+	}
+	if len(buf) > 0 && buf[len(buf)-1] >= limit {
+		return 0, errUnterminatedSequence
+	}
+	return n, nil
+}
+
+// MaxCoordBytes returns the length in bytes of the longest encoding of a
+// single coordinate in buf, and any error. It does not decode the coordinates.
+// Like CountCoords, it returns errDimensionalMismatch if the number of encoded
+// integers is not a multiple of c.Dim.
+func (c Codec) MaxCoordBytes(buf []byte) (int, error) {
+	if err := c.validate(); err != nil {
+		return 0, err
+	}
+	limit := c.terminatorLimit()
+	maxBytes, start, ints := 0, 0, 0
+	for i, b := range buf {
+		switch {
+		case 63 <= b && b < limit:
+			ints++
+			if ints == c.Dim {
+				if n := i + 1 - start; n > maxBytes {
+					maxBytes = n
+				}
+				start, ints = i+1, 0
+			}
+		case limit <= b && b < 2*limit-63:
+		default:
+			return 0, errInvalidByte
+		}
+	}
+	if len(buf) > 0 && buf[len(buf)-1] >= limit {
+		return 0, errUnterminatedSequence
+	}
+	if ints != 0 {
+		return 0, errDimensionalMismatch
+	}
+	return maxBytes, nil
+}
+
+// countInts returns the number of integers encoded in buf and any error.
+func countInts(buf []byte) (int, error) {
+	n := 0
+	for _, b := range buf {
+		switch {
+		case 63 <= b && b < 95:
+			n++
+		case 95 <= b && b < 127:
+		default:
+			return 0, errInvalidByte
+		}
 	}
-	return EncodeUint(buf, u)
+	if len(buf) > 0 && buf[len(buf)-1] >= 95 {
+		return 0, errUnterminatedSequence
+	}
+	return n, nil
 }
 
 // DecodeCoord decodes a single coordinate from buf. It returns the coordinate,
 // the remaining unconsumed bytes of buf, and any error.
 func (c Codec) DecodeCoord(buf []byte) ([]float64, []byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, nil, err
+	}
 	coord := make([]float64, c.Dim)
 	for i := range coord {
 		var err error
 		var j int
-		j, buf, err = DecodeInt(buf)
+		j, buf, err = c.DecodeInt(buf)
 		if err != nil {
 			return nil, nil, err
 		}
-		coord[i] = float64(j) / c.Scale
+		coord[i] = c.toFloat(i, j)
 	}
 	return coord, buf, nil
 }
 
+// DecodeDeltaInts decodes a single coordinate's worth of integer deltas, in
+// scaled integer units, from buf. It returns the deltas, the remaining
+// unconsumed bytes of buf, and any error.
+func (c Codec) DecodeDeltaInts(buf []byte) ([]int, []byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, nil, err
+	}
+	delta := make([]int, c.Dim)
+	for i := range delta {
+		var err error
+		delta[i], buf, err = c.DecodeInt(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return delta, buf, nil
+}
+
 // DecodeCoords decodes an array of coordinates from buf. It returns the
-// coordinates, the remaining unconsumed bytes of buf, and any error.
+// coordinates, the remaining unconsumed bytes of buf, and any error. It
+// consumes all of buf, so the remaining bytes are always empty; use
+// DecodeCoordsN to decode coordinates that are followed by other data.
 func (c Codec) DecodeCoords(buf []byte) ([][]float64, []byte, error) {
+	return c.DecodeCoordsHint(buf, 1)
+}
+
+// DecodeCoordsInto decodes an array of coordinates from buf into dst, reusing
+// the capacity of dst and any coordinate slices within that capacity that can
+// hold c.Dim values, so that repeated decoding into the same dst allocates only
+// when it needs to grow. The previous contents of dst are overwritten. It
+// returns the coordinates, the remaining unconsumed bytes of buf, and any
+// error. On error the returned coordinates are dst[:0], so that dst can still
+// be reused.
+func (c Codec) DecodeCoordsInto(dst [][]float64, buf []byte) ([][]float64, []byte, error) {
+	if err := c.validate(); err != nil {
+		return dst[:0], nil, err
+	}
+	n, err := c.countInts(buf)
+	if err != nil {
+		return dst[:0], nil, err
+	}
+	if n == 0 || n%c.Dim != 0 {
+		return dst[:0], nil, errUnterminatedSequence
+	}
+	m := n / c.Dim
+	if cap(dst) < m {
+		dst = append(dst[:cap(dst)], make([][]float64, m-cap(dst))...)
+	}
+	dst = dst[:m]
+	last := make([]int, c.Dim)
+	var flatCoords []float64
+	for i := range dst {
+		coord := dst[i]
+		if cap(coord) >= c.Dim {
+			coord = coord[:c.Dim]
+		} else {
+			if len(flatCoords) == 0 {
+				flatCoords = make([]float64, (m-i)*c.Dim)
+			}
+			coord = flatCoords[:c.Dim:c.Dim]
+			flatCoords = flatCoords[c.Dim:]
+		}
+		if buf, err = c.decodeCoord(buf, last, coord); err != nil {
+			return dst[:0], nil, err
+		}
+		dst[i] = coord
+	}
+	return dst, buf, nil
+}
+
+// DecodeCoordsN decodes exactly n coordinates from the start of buf. It returns
+// the coordinates, the remaining unconsumed bytes of buf, and any error. Unlike
+// DecodeCoords it can be used to decode coordinates that are followed by other
+// data.
+func (c Codec) DecodeCoordsN(buf []byte, n int) ([][]float64, []byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, nil, err
+	}
+	if n < 0 {
+		return nil, nil, errCountMismatch
+	}
+	if n > len(buf)/c.Dim {
+		return nil, nil, errUnterminatedSequence
+	}
+	flatCoords := make([]float64, n*c.Dim)
+	coords := make([][]float64, n)
+	last := make([]int, c.Dim)
+	for i := range coords {
+		coord := flatCoords[i*c.Dim : (i+1)*c.Dim : (i+1)*c.Dim]
+		var err error
+		if buf, err = c.decodeCoord(buf, last, coord); err != nil {
+			return nil, nil, err
+		}
+		coords[i] = coord
+	}
+	return coords, buf, nil
+}
+
+// DecodeCoordsHint decodes an array of coordinates from buf, preallocating
+// space for sizeHint coordinates. It returns the coordinates, the remaining
+// unconsumed bytes of buf, and any error.
+func (c Codec) DecodeCoordsHint(buf []byte, sizeHint int) ([][]float64, []byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, nil, err
+	}
+	if sizeHint < 1 {
+		sizeHint = 1
+	}
+	if c.isDefault() {
+		return decodeCoordsDefault(buf)
+	}
+	return c.decodeCoordsHint(buf, sizeHint)
+}
+
+// decodeCoordsHint is the generic implementation of DecodeCoordsHint.
+func (c Codec) decodeCoordsHint(buf []byte, sizeHint int) ([][]float64, []byte, error) {
 	var coord []float64
 	var err error
 	coord, buf, err = c.DecodeCoord(buf)
 	if err != nil {
 		return nil, nil, err
 	}
-	coords := [][]float64{coord}
+	coords := make([][]float64, 1, sizeHint)
+	coords[0] = coord
 	for i := 1; len(buf) > 0; i++ {
 		coord, buf, err = c.DecodeCoord(buf)
 		if err != nil {
@@ -134,33 +781,578 @@ func (c Codec) DecodeCoords(buf []byte) ([][]float64, []byte, error) {
 	return coords, nil, nil
 }
 
-// DecodeFlatCoords decodes coordinates from buf, appending them to a
-// one-dimensional array. It returns the coordinates, the remaining unconsumed
-// bytes in buf, and any error.
-func (c Codec) DecodeFlatCoords(flatCoords []float64, buf []byte) ([]float64, []byte, error) {
-	if len(flatCoords)%c.Dim != 0 {
-		return nil, nil, errDimensionalMismatch
+// DecodeCoordsExpect decodes an array of coordinates from buf and checks that
+// there are exactly expected coordinates. It returns the coordinates and any
+// error.
+func (c Codec) DecodeCoordsExpect(buf []byte, expected int) ([][]float64, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
 	}
-	last := make([]int, c.Dim)
-	for len(buf) > 0 {
-		for j := 0; j < c.Dim; j++ {
-			var err error
-			var k int
-			k, buf, err = DecodeInt(buf)
-			if err != nil {
-				return nil, nil, err
-			}
-			last[j] += k
-			flatCoords = append(flatCoords, float64(last[j])/c.Scale)
-		}
+	if expected < 0 {
+		return nil, errCountMismatch
 	}
-	return flatCoords, nil, nil
+	sizeHint := expected
+	if n := len(buf) / c.Dim; sizeHint > n {
+		sizeHint = n
+	}
+	coords, _, err := c.DecodeCoordsHint(buf, sizeHint)
+	if err != nil {
+		return nil, err
+	}
+	if len(coords) != expected {
+		return nil, errCountMismatch
+	}
+	return coords, nil
 }
 
-// EncodeCoord encodes a single coordinate to buf and returns the new buf.
-func (c Codec) EncodeCoord(buf []byte, coord []float64) []byte {
-	for _, x := range coord {
-		buf = EncodeInt(buf, round(c.Scale*x))
+// DecodeCoordsArena decodes an array of coordinates from buf into a single
+// contiguous backing array, using one allocation for the coordinate values and
+// one for the coordinates. It returns the coordinates, the backing array, and
+// any error. The coordinates share the backing array, so modifying one
+// modifies the other.
+func (c Codec) DecodeCoordsArena(buf []byte) ([][]float64, []float64, error) {
+	if err := c.validate(); err != nil {
+		return nil, nil, err
+	}
+	n, err := c.countInts(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n == 0 {
+		return nil, nil, errUnterminatedSequence
+	}
+	flatCoords, _, err := c.DecodeFlatCoords(make([]float64, 0, n), buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	coords := make([][]float64, len(flatCoords)/c.Dim)
+	for i := range coords {
+		coords[i] = flatCoords[i*c.Dim : (i+1)*c.Dim : (i+1)*c.Dim]
+	}
+	return coords, flatCoords, nil
+}
+
+// DecodeCoordsPartial decodes an array of coordinates from buf. Unlike
+// DecodeCoords, if an error occurs it returns the coordinates decoded before
+// the error. It returns the coordinates, the number of bytes of buf consumed by
+// those coordinates, and any error. On error, decoding can be resumed from
+// buf[consumed:] with DecodeCoordsFrom, using the last returned coordinate in
+// scaled integer units as the origin.
+func (c Codec) DecodeCoordsPartial(buf []byte) (coords [][]float64, consumed int, err error) {
+	if err := c.validate(); err != nil {
+		return nil, 0, err
+	}
+	last := make([]int, c.Dim)
+	rest := buf
+	for {
+		coord := make([]float64, c.Dim)
+		if rest, err = c.decodeCoord(rest, last, coord); err != nil {
+			return coords, consumed, err
+		}
+		coords = append(coords, coord)
+		consumed = len(buf) - len(rest)
+		if len(rest) == 0 {
+			return coords, consumed, nil
+		}
+	}
+}
+
+// DecodeCoords32 decodes an array of coordinates from buf as float32s, which
+// uses half the memory of DecodeCoords. float32s have roughly seven significant
+// decimal digits, so for coordinates scaled by 1e5 the additional error is at
+// most a few millionths of a degree. It returns the coordinates and any error.
+func (c Codec) DecodeCoords32(buf []byte) ([][]float32, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	var coords [][]float32
+	last := make([]int, c.Dim)
+	for {
+		var err error
+		if buf, err = c.decodeInts(buf, last); err != nil {
+			return nil, err
+		}
+		coord := make([]float32, c.Dim)
+		for j, x := range last {
+			coord[j] = float32(c.toFloat(j, x))
+		}
+		coords = append(coords, coord)
+		if len(buf) == 0 {
+			return coords, nil
+		}
+	}
+}
+
+// DecodeIntBounds decodes buf and returns the componentwise minimum and maximum
+// of the coordinates in scaled integer units, and any error. An empty buf is
+// an error.
+func (c Codec) DecodeIntBounds(buf []byte) (min, max []int, err error) {
+	if err := c.validate(); err != nil {
+		return nil, nil, err
+	}
+	last := make([]int, c.Dim)
+	min = make([]int, c.Dim)
+	max = make([]int, c.Dim)
+	for first := true; first || len(buf) > 0; first = false {
+		if buf, err = c.decodeInts(buf, last); err != nil {
+			return nil, nil, err
+		}
+		for j := range last {
+			if first || last[j] < min[j] {
+				min[j] = last[j]
+			}
+			if first || last[j] > max[j] {
+				max[j] = last[j]
+			}
+		}
+	}
+	return min, max, nil
+}
+
+// DecodeCoordsFrom decodes an array of coordinates from buf relative to origin,
+// so that the first coordinate is origin plus the first delta rather than the
+// first delta alone. origin is in scaled integer units and must have length
+// c.Dim. It returns the coordinates and any error.
+func (c Codec) DecodeCoordsFrom(origin []int, buf []byte) ([][]float64, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if len(origin) != c.Dim {
+		return nil, errDimensionalMismatch
+	}
+	last := append([]int(nil), origin...)
+	var coords [][]float64
+	for {
+		coord := make([]float64, c.Dim)
+		var err error
+		if buf, err = c.decodeCoord(buf, last, coord); err != nil {
+			return nil, err
+		}
+		coords = append(coords, coord)
+		if len(buf) == 0 {
+			return coords, nil
+		}
+	}
+}
+
+// DecodeCoordsMaxDelta decodes an array of coordinates from buf and also
+// returns the largest Euclidean distance between successive coordinates, in
+// scaled integer units, and any error. A single coordinate has a maximum delta
+// of zero.
+func (c Codec) DecodeCoordsMaxDelta(buf []byte) ([][]float64, float64, error) {
+	if err := c.validate(); err != nil {
+		return nil, 0, err
+	}
+	last := make([]int, c.Dim)
+	prev := make([]int, c.Dim)
+	var coords [][]float64
+	maxDelta := 0.0
+	for first := true; first || len(buf) > 0; first = false {
+		copy(prev, last)
+		coord := make([]float64, c.Dim)
+		var err error
+		if buf, err = c.decodeCoord(buf, last, coord); err != nil {
+			return nil, 0, err
+		}
+		sumSquares := 0.0
+		for j, x := range last {
+			k := float64(x - prev[j])
+			sumSquares += k * k
+		}
+		if delta := math.Sqrt(sumSquares); !first && delta > maxDelta {
+			maxDelta = delta
+		}
+		coords = append(coords, coord)
+	}
+	return coords, maxDelta, nil
+}
+
+// DecodeWindow decodes coordinates from buf, calling fn after each coordinate
+// with a window of up to the n most recently decoded coordinates, oldest first.
+// The window and the coordinates in it are reused between calls, so fn must
+// copy any it wants to retain. If fn returns an error then decoding stops and
+// the error is returned. n must be positive.
+func (c Codec) DecodeWindow(buf []byte, n int, fn func(window [][]float64) error) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if n < 1 {
+		return errInvalidWindow
+	}
+	last := make([]int, c.Dim)
+	window := make([][]float64, 0, n)
+	for first := true; first || len(buf) > 0; first = false {
+		var coord []float64
+		if len(window) < n {
+			coord = make([]float64, c.Dim)
+		} else {
+			coord = window[0]
+			copy(window, window[1:])
+			window = window[:n-1]
+		}
+		var err error
+		if buf, err = c.decodeCoord(buf, last, coord); err != nil {
+			return err
+		}
+		window = append(window, coord)
+		if err := fn(window); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeCoordsWithAltCheck decodes an array of three-dimensional coordinates
+// from buf, where the third dimension is altitude in meters. It returns the
+// coordinates, the indexes of coordinates whose altitude differs from the
+// previous coordinate's by more than maxAltDeltaMeters, and any error. c.Dim
+// must be 3.
+func (c Codec) DecodeCoordsWithAltCheck(buf []byte, maxAltDeltaMeters float64) ([][]float64, []int, error) {
+	if err := c.validate(); err != nil {
+		return nil, nil, err
+	}
+	if c.Dim != 3 {
+		return nil, nil, errDimensionalMismatch
+	}
+	var last [3]int
+	var coords [][]float64
+	var flagged []int
+	for i := 0; i == 0 || len(buf) > 0; i++ {
+		coord := make([]float64, 3)
+		var err error
+		if buf, err = c.decodeCoord(buf, last[:], coord); err != nil {
+			return nil, nil, err
+		}
+		if i > 0 && math.Abs(coord[2]-coords[i-1][2]) > maxAltDeltaMeters {
+			flagged = append(flagged, i)
+		}
+		coords = append(coords, coord)
+	}
+	return coords, flagged, nil
+}
+
+// DecodeRing decodes coordinates from buf through a ring buffer holding at most
+// capacity coordinates, so that memory use is bounded by capacity rather than
+// by the length of buf. When the ring is full, each newly decoded coordinate
+// evicts the oldest, and fn, if not nil, is called with the evicted coordinate.
+// The evicted coordinate's memory is reused after fn returns, so fn must copy
+// it to retain it. It returns the last capacity coordinates, oldest first, and
+// any error. capacity must be positive.
+func (c Codec) DecodeRing(buf []byte, capacity int, fn func(evicted []float64)) ([][]float64, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if capacity < 1 {
+		return nil, errInvalidCapacity
+	}
+	last := make([]int, c.Dim)
+	ring := make([][]float64, 0, capacity)
+	head := 0
+	for first := true; first || len(buf) > 0; first = false {
+		var coord []float64
+		if len(ring) < capacity {
+			coord = make([]float64, c.Dim)
+			ring = append(ring, coord)
+		} else {
+			coord = ring[head]
+			if fn != nil {
+				fn(coord)
+			}
+			head = (head + 1) % capacity
+		}
+		var err error
+		if buf, err = c.decodeCoord(buf, last, coord); err != nil {
+			return nil, err
+		}
+	}
+	return append(ring[head:len(ring):len(ring)], ring[:head]...), nil
+}
+
+// DecodeStats are statistics about a decoded polyline. Bytes is the total
+// number of bytes, ContinuationBytes is the number of bytes that are not the
+// final byte of an integer, and AvgDeltaLen is the average length in bytes of
+// the encoded deltas of each dimension.
+type DecodeStats struct {
+	Bytes             int
+	ContinuationBytes int
+	AvgDeltaLen       []float64
+}
+
+// DecodeCoordsWithStats decodes an array of coordinates from buf and returns
+// the coordinates, statistics about the encoding, and any error.
+func (c Codec) DecodeCoordsWithStats(buf []byte) ([][]float64, DecodeStats, error) {
+	if err := c.validate(); err != nil {
+		return nil, DecodeStats{}, err
+	}
+	stats := DecodeStats{
+		Bytes:       len(buf),
+		AvgDeltaLen: make([]float64, c.Dim),
+	}
+	last := make([]int, c.Dim)
+	var coords [][]float64
+	for first := true; first || len(buf) > 0; first = false {
+		for j := range last {
+			n := len(buf)
+			var err error
+			if buf, err = c.decodeInts(buf, last[j:j+1]); err != nil {
+				return nil, DecodeStats{}, err
+			}
+			stats.AvgDeltaLen[j] += float64(n - len(buf))
+		}
+		coord := make([]float64, c.Dim)
+		for j, x := range last {
+			coord[j] = c.toFloat(j, x)
+		}
+		coords = append(coords, coord)
+	}
+	for j := range stats.AvgDeltaLen {
+		stats.AvgDeltaLen[j] /= float64(len(coords))
+	}
+	stats.ContinuationBytes = stats.Bytes - len(coords)*c.Dim
+	return coords, stats, nil
+}
+
+// A Cursor records the position of a resumable decode. Last is the last
+// decoded coordinate in scaled integer units and Offset is the number of bytes
+// consumed. The zero Cursor is positioned at the start of a polyline.
+type Cursor struct {
+	Last   []int
+	Offset int
+}
+
+// DecodeCoordsResume decodes up to max coordinates from buf starting at cur,
+// or all remaining coordinates if max is not positive. It returns the
+// coordinates, a Cursor positioned after them, and any error. When cur is at
+// the end of buf it returns no coordinates and cur.
+func (c Codec) DecodeCoordsResume(buf []byte, cur Cursor, max int) ([][]float64, Cursor, error) {
+	if err := c.validate(); err != nil {
+		return nil, cur, err
+	}
+	last := make([]int, c.Dim)
+	if cur.Last != nil {
+		if len(cur.Last) != c.Dim {
+			return nil, cur, errDimensionalMismatch
+		}
+		copy(last, cur.Last)
+	}
+	if cur.Offset < 0 || cur.Offset > len(buf) {
+		return nil, cur, errIndexOutOfRange
+	}
+	rest := buf[cur.Offset:]
+	if len(rest) == 0 {
+		return nil, cur, nil
+	}
+	var coords [][]float64
+	for len(rest) > 0 && (max <= 0 || len(coords) < max) {
+		coord := make([]float64, c.Dim)
+		var err error
+		if rest, err = c.decodeCoord(rest, last, coord); err != nil {
+			return nil, cur, err
+		}
+		coords = append(coords, coord)
+	}
+	return coords, Cursor{Last: last, Offset: len(buf) - len(rest)}, nil
+}
+
+// IsClosed returns whether the polyline encoded in buf has at least two
+// coordinates and its first and last coordinates are equal at c's precision,
+// and any error. An empty buf is an error.
+func (c Codec) IsClosed(buf []byte) (bool, error) {
+	if err := c.validate(); err != nil {
+		return false, err
+	}
+	var first []int
+	last := make([]int, c.Dim)
+	n := 0
+	for ; n == 0 || len(buf) > 0; n++ {
+		var err error
+		if buf, err = c.decodeInts(buf, last); err != nil {
+			return false, err
+		}
+		if n == 0 {
+			first = append(first, last...)
+		}
+	}
+	if n < 2 {
+		return false, nil
+	}
+	for j := range last {
+		if last[j] != first[j] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RotateRing re-encodes the ring encoded in buf so that the vertex at
+// startIndex becomes its first vertex, without changing its geometry. If the
+// ring is closed, with its first and last coordinates equal at c's precision,
+// then the closing coordinate is not counted as a vertex and the result is
+// closed at the new first vertex. It returns the new encoding and any error.
+func (c Codec) RotateRing(buf []byte, startIndex int) ([]byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	flatInts, err := c.DecodeFlatInts(buf)
+	if err != nil {
+		return nil, err
+	}
+	n := len(flatInts) / c.Dim
+	closed := n >= 2
+	for j := 0; closed && j < c.Dim; j++ {
+		closed = flatInts[j] == flatInts[(n-1)*c.Dim+j]
+	}
+	if closed {
+		n--
+	}
+	if startIndex < 0 || startIndex >= n {
+		return nil, errIndexOutOfRange
+	}
+	rotated := make([]byte, 0, len(buf))
+	last := make([]int, c.Dim)
+	m := n
+	if closed {
+		m++
+	}
+	for i := 0; i < m; i++ {
+		coord := flatInts[(startIndex+i)%n*c.Dim:]
+		for j := range last {
+			rotated = c.EncodeInt(rotated, coord[j]-last[j])
+			last[j] = coord[j]
+		}
+	}
+	return rotated, nil
+}
+
+// DecodeFlatCoords decodes coordinates from buf, appending them to a
+// one-dimensional array. If flatCoords is not empty then the first coordinate
+// in buf is decoded relative to the last coordinate in flatCoords, so a
+// polyline split into several chunks can be decoded one chunk at a time. It
+// returns the coordinates, the remaining unconsumed bytes in buf, and any
+// error.
+func (c Codec) DecodeFlatCoords(flatCoords []float64, buf []byte) ([]float64, []byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, nil, err
+	}
+	if len(flatCoords)%c.Dim != 0 {
+		return nil, nil, errDimensionalMismatch
+	}
+	last := make([]int, c.Dim)
+	if n := len(flatCoords); n > 0 {
+		for j := range last {
+			last[j] = c.toInt(j, flatCoords[n-c.Dim+j])
+		}
+	}
+	for len(buf) > 0 {
+		var err error
+		if buf, err = c.decodeInts(buf, last); err != nil {
+			return nil, nil, err
+		}
+		for j, x := range last {
+			flatCoords = append(flatCoords, c.toFloat(j, x))
+		}
+	}
+	return flatCoords, nil, nil
+}
+
+// DecodeFlatInts decodes coordinates from buf as scaled integers in a
+// one-dimensional array, without converting them to floats. It returns the
+// scaled integers and any error.
+func (c Codec) DecodeFlatInts(buf []byte) ([]int, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	n, err := c.countInts(buf)
+	if err != nil {
+		return nil, err
+	}
+	if n%c.Dim != 0 {
+		return nil, errUnterminatedSequence
+	}
+	flatInts := make([]int, n)
+	for i := range flatInts {
+		var k int
+		k, buf, err = c.DecodeInt(buf)
+		if err != nil {
+			return nil, err
+		}
+		if i >= c.Dim {
+			k += flatInts[i-c.Dim]
+		}
+		flatInts[i] = k
+	}
+	return flatInts, nil
+}
+
+// DecodeFlatInt32 decodes coordinates from buf as scaled integers, appending
+// them to a one-dimensional array without converting them to floats. It
+// returns the scaled integers and any error. If any scaled integer does not fit
+// in an int32 then it returns an error.
+func (c Codec) DecodeFlatInt32(dst []int32, buf []byte) ([]int32, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if len(dst)%c.Dim != 0 {
+		return nil, errDimensionalMismatch
+	}
+	last := make([]int, c.Dim)
+	for len(buf) > 0 {
+		var err error
+		if buf, err = c.decodeInts(buf, last); err != nil {
+			return nil, err
+		}
+		for _, x := range last {
+			if x < math.MinInt32 || math.MaxInt32 < x {
+				return nil, errOverflow
+			}
+			dst = append(dst, int32(x))
+		}
+	}
+	return dst, nil
+}
+
+// Deltas decodes the signed integer deltas from buf without scaling or
+// accumulating them. It returns one slice of Dim deltas per coordinate and any
+// error.
+func (c Codec) Deltas(buf []byte) ([][]int, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	var flatDeltas []int
+	for len(buf) > 0 {
+		for j := 0; j < c.Dim; j++ {
+			var err error
+			var k int
+			k, buf, err = c.DecodeInt(buf)
+			if err != nil {
+				return nil, err
+			}
+			flatDeltas = append(flatDeltas, k)
+		}
+	}
+	deltas := make([][]int, 0, len(flatDeltas)/c.Dim)
+	for i := 0; i < len(flatDeltas); i += c.Dim {
+		deltas = append(deltas, flatDeltas[i:i+c.Dim:i+c.Dim])
+	}
+	return deltas, nil
+}
+
+// EncodeCoord encodes a single coordinate to buf and returns the new buf.
+func (c Codec) EncodeCoord(buf []byte, coord []float64) []byte {
+	c.mustValidate()
+	for i, x := range coord {
+		buf = c.EncodeInt(buf, c.toInt(i, x))
+	}
+	return buf
+}
+
+// EncodeDeltaInts appends a single coordinate's worth of integer deltas, in
+// scaled integer units, to buf and returns the new buf. delta should have
+// length c.Dim.
+func (c Codec) EncodeDeltaInts(buf []byte, delta []int) []byte {
+	c.mustValidate()
+	for _, i := range delta {
+		buf = c.EncodeInt(buf, i)
 	}
 	return buf
 }
@@ -168,28 +1360,543 @@ func (c Codec) EncodeCoord(buf []byte, coord []float64) []byte {
 // EncodeCoords appends the encoding of an array of coordinates coords to buf
 // and returns the new buf.
 func (c Codec) EncodeCoords(buf []byte, coords [][]float64) []byte {
+	c.mustValidate()
+	if c.isDefault() {
+		return encodeCoordsDefault(buf, coords)
+	}
+	return c.encodeCoords(buf, make([]int, c.Dim), coords)
+}
+
+// intLen returns the length of the encoding of i using c's chunk bits.
+func (c Codec) intLen(i int) int {
+	u := uint(i) << 1
+	if i < 0 {
+		u = ^u
+	}
+	if u == 0 {
+		return 1
+	}
+	k := int(c.chunkBits())
+	return (bits.Len(u) + k - 1) / k
+}
+
+// EncodedLen returns the exact number of bytes that EncodeCoords appends when
+// encoding coords.
+func (c Codec) EncodedLen(coords [][]float64) int {
+	c.mustValidate()
+	n := 0
 	last := make([]int, c.Dim)
 	for _, coord := range coords {
 		for i, x := range coord {
-			ex := round(c.Scale * x)
-			buf = EncodeInt(buf, ex-last[i])
+			ex := c.toInt(i, x)
+			n += c.intLen(ex - last[i])
 			last[i] = ex
 		}
 	}
+	return n
+}
+
+// EncodedLenFlat returns the exact number of bytes that EncodeFlatCoords
+// appends when encoding flatCoords, whose length should be a multiple of
+// c.Dim.
+func (c Codec) EncodedLenFlat(flatCoords []float64) int {
+	c.mustValidate()
+	n := 0
+	last := make([]int, c.Dim)
+	for i, x := range flatCoords {
+		j := i % c.Dim
+		ex := c.toInt(j, x)
+		n += c.intLen(ex - last[j])
+		last[j] = ex
+	}
+	return n
+}
+
+// EncodeCoordsFixed writes the encoding of an array of coordinates coords to
+// the start of dst, overwriting its contents rather than appending to it. It
+// returns the number of bytes written and any error. If dst is too small to
+// hold the encoding then it returns errBufferTooSmall, in which case dst may
+// have been partially overwritten. On success it does not allocate. Use
+// EncodedLen to size dst.
+func (c Codec) EncodeCoordsFixed(dst []byte, coords [][]float64) (int, error) {
+	if err := c.validate(); err != nil {
+		return 0, err
+	}
+	n := 0
+	for i, coord := range coords {
+		for j, x := range coord {
+			delta := c.toInt(j, x)
+			if i > 0 {
+				delta -= c.toInt(j, coords[i-1][j])
+			}
+			encoded := c.EncodeInt(dst[n:n:len(dst)], delta)
+			if len(encoded) > len(dst)-n {
+				return 0, errBufferTooSmall
+			}
+			n += len(encoded)
+		}
+	}
+	return n, nil
+}
+
+// EncodeCoordsChecked appends the encoding of an array of coordinates coords to
+// buf, like EncodeCoords, and checks that every encoded byte is printable 7-bit
+// ASCII, i.e. in the standard encoding's range of 63 to 126. It returns the new
+// buf and any error. This guards transports that only accept text. Of the valid
+// chunk bits, only 6 produces other bytes: its continuation bytes range from
+// 127, which is DEL, to 190.
+func (c Codec) EncodeCoordsChecked(buf []byte, coords [][]float64) ([]byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	start := len(buf)
+	buf = c.EncodeCoords(buf, coords)
+	for _, b := range buf[start:] {
+		if b < 63 || b > 126 {
+			return nil, errNonASCII
+		}
+	}
+	return buf, nil
+}
+
+// EncodeCoordsFrom appends the encoding of an array of coordinates coords to
+// buf, relative to origin, and returns the new buf and any error. origin is in
+// scaled integer units and must have length c.Dim.
+func (c Codec) EncodeCoordsFrom(buf []byte, origin []int, coords [][]float64) ([]byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if len(origin) != c.Dim {
+		return nil, errDimensionalMismatch
+	}
+	return c.encodeCoords(buf, append([]int(nil), origin...), coords), nil
+}
+
+// encodeCoords appends the encoding of coords to buf, computing deltas
+// relative to last, which is modified.
+func (c Codec) encodeCoords(buf []byte, last []int, coords [][]float64) []byte {
+	for _, coord := range coords {
+		for i, x := range coord {
+			ex := c.toInt(i, x)
+			buf = c.EncodeInt(buf, ex-last[i])
+			last[i] = ex
+		}
+	}
+	return buf
+}
+
+// EncodeCoordsQuantized appends the encoding of an array of coordinates coords
+// to buf, first rounding each value to the nearest multiple of 1/gridScale, in
+// the same units as c.Scale, and returns the new buf. gridScale should be less
+// than or equal to the scale of each dimension; coarser grids give smaller, more
+// repetitive deltas at the cost of precision. It panics if gridScale is not
+// positive.
+func (c Codec) EncodeCoordsQuantized(buf []byte, coords [][]float64, gridScale float64) []byte {
+	c.mustValidate()
+	if !(gridScale > 0) {
+		panic("polyline: grid scale must be positive")
+	}
+	last := make([]int, c.Dim)
+	for _, coord := range coords {
+		for i, x := range coord {
+			step := c.scale(i) / gridScale
+			ex := round(float64(round(float64(c.toInt(i, x))/step)) * step)
+			buf = c.EncodeInt(buf, ex-last[i])
+			last[i] = ex
+		}
+	}
+	return buf
+}
+
+// EncodeColumns appends the encoding of coordinates stored column-wise to buf,
+// where cols contains one slice of values per dimension. It returns the new buf
+// and any error. All columns must have the same length.
+func (c Codec) EncodeColumns(buf []byte, cols ...[]float64) ([]byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if c.Dim < 1 || len(cols) != c.Dim {
+		return nil, errDimensionalMismatch
+	}
+	for _, col := range cols[1:] {
+		if len(col) != len(cols[0]) {
+			return nil, errLengthMismatch
+		}
+	}
+	last := make([]int, c.Dim)
+	for i := range cols[0] {
+		for j, col := range cols {
+			ex := c.toInt(j, col[i])
+			buf = c.EncodeInt(buf, ex-last[j])
+			last[j] = ex
+		}
+	}
+	return buf, nil
+}
+
+// DecodePerDimension decodes coordinates from buf and returns them as c.Dim
+// independent channels, each holding that dimension's values over all
+// coordinates, and any error. This suits multi-dimensional polylines where each
+// dimension is a separate signal, such as a time channel.
+func (c Codec) DecodePerDimension(buf []byte) ([][]float64, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	flatInts, err := c.DecodeFlatInts(buf)
+	if err != nil {
+		return nil, err
+	}
+	n := len(flatInts) / c.Dim
+	channels := make([][]float64, c.Dim)
+	for j := range channels {
+		channel := make([]float64, n)
+		for i := range channel {
+			channel[i] = c.toFloat(j, flatInts[i*c.Dim+j])
+		}
+		channels[j] = channel
+	}
+	return channels, nil
+}
+
+// EncodePerDimension appends the encoding of c.Dim independent channels to buf,
+// combining the ith value of each channel into the ith coordinate. It is the
+// inverse of DecodePerDimension. It returns the new buf and any error. All
+// channels must have the same length.
+func (c Codec) EncodePerDimension(buf []byte, channels [][]float64) ([]byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c.EncodeColumns(buf, channels...)
+}
+
+// AppendRaw appends the already-encoded polyline segment to buf, which ends
+// with a coordinate at priorLast, in scaled integer units. Only the first
+// coordinate of segment is re-encoded, as a delta from priorLast; the remaining
+// bytes of segment are appended unchanged. It returns the new buf, the last
+// coordinate of the result in scaled integer units, and any error.
+func (c Codec) AppendRaw(buf, segment []byte, priorLast []int) ([]byte, []int, error) {
+	if err := c.validate(); err != nil {
+		return nil, nil, err
+	}
+	if len(priorLast) != c.Dim {
+		return nil, nil, errDimensionalMismatch
+	}
+	last := append([]int(nil), priorLast...)
+	if len(segment) == 0 {
+		return buf, last, nil
+	}
+	rest := segment
+	for j := range last {
+		var err error
+		var k int
+		k, rest, err = c.DecodeInt(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		buf = c.EncodeInt(buf, k-last[j])
+		last[j] = k
+	}
+	for tail := rest; len(tail) > 0; {
+		var err error
+		if tail, err = c.decodeInts(tail, last); err != nil {
+			return nil, nil, err
+		}
+	}
+	return append(buf, rest...), last, nil
+}
+
+// wrapInt returns x wrapped into the range [-period/2, period/2).
+func wrapInt(x, period int) int {
+	half := period / 2
+	return ((x+half)%period+period)%period - half
+}
+
+// EncodeCoordsUnwrapped appends the encoding of an array of coordinates coords
+// to buf, unwrapping longitudes (the second dimension) so that a route crossing
+// the antimeridian is encoded as a small delta rather than a jump of nearly
+// 360 degrees. It assumes that successive coordinates are less than 180 degrees
+// of longitude apart. It returns the new buf. Use DecodeCoordsWrapped to
+// decode the result.
+func (c Codec) EncodeCoordsUnwrapped(buf []byte, coords [][]float64) []byte {
+	c.mustValidate()
+	period := round(c.scale(1) * 360)
+	last := make([]int, c.Dim)
+	for i, coord := range coords {
+		for j, x := range coord {
+			ex := c.toInt(j, x)
+			delta := ex - last[j]
+			if j == 1 && i > 0 {
+				delta = wrapInt(delta, period)
+			}
+			buf = c.EncodeInt(buf, delta)
+			last[j] = ex
+		}
+	}
 	return buf
 }
 
+// DecodeCoordsWrapped decodes an array of coordinates from buf, normalizing
+// longitudes (the second dimension) into the range [-180, 180). It decodes
+// polylines encoded with EncodeCoordsUnwrapped. It returns the coordinates, the
+// remaining unconsumed bytes of buf, and any error.
+func (c Codec) DecodeCoordsWrapped(buf []byte) ([][]float64, []byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, nil, err
+	}
+	period := round(c.scale(1) * 360)
+	last := make([]int, c.Dim)
+	var coords [][]float64
+	for first := true; first || len(buf) > 0; first = false {
+		var err error
+		if buf, err = c.decodeInts(buf, last); err != nil {
+			return nil, nil, err
+		}
+		coord := make([]float64, c.Dim)
+		for j, x := range last {
+			if j == 1 {
+				x = wrapInt(x, period)
+			}
+			coord[j] = c.toFloat(j, x)
+		}
+		coords = append(coords, coord)
+	}
+	return coords, nil, nil
+}
+
+// validPermutation returns whether perm is a permutation of 0..c.Dim-1.
+func (c Codec) validPermutation(perm []int) bool {
+	if len(perm) != c.Dim {
+		return false
+	}
+	seen := make([]bool, c.Dim)
+	for _, p := range perm {
+		if p < 0 || p >= c.Dim || seen[p] {
+			return false
+		}
+		seen[p] = true
+	}
+	return true
+}
+
+// EncodeCoordsPermuted appends the encoding of an array of coordinates coords
+// to buf with their components reordered by perm, so that component i of each
+// encoded coordinate is component perm[i] of the corresponding coordinate in
+// coords. perm must be a permutation of 0..c.Dim-1. It returns the new buf and
+// any error.
+func (c Codec) EncodeCoordsPermuted(buf []byte, coords [][]float64, perm []int) ([]byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if !c.validPermutation(perm) {
+		return nil, errInvalidPermutation
+	}
+	last := make([]int, c.Dim)
+	for _, coord := range coords {
+		if len(coord) != c.Dim {
+			return nil, errDimensionalMismatch
+		}
+		for i, p := range perm {
+			ex := c.toInt(i, coord[p])
+			buf = c.EncodeInt(buf, ex-last[i])
+			last[i] = ex
+		}
+	}
+	return buf, nil
+}
+
+// DecodeCoordsPermuted decodes an array of coordinates from buf and reorders
+// their components by the inverse of perm, so that it reverses
+// EncodeCoordsPermuted with the same perm. perm must be a permutation of
+// 0..c.Dim-1. It returns the coordinates and any error.
+func (c Codec) DecodeCoordsPermuted(buf []byte, perm []int) ([][]float64, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if !c.validPermutation(perm) {
+		return nil, errInvalidPermutation
+	}
+	coords, _, err := c.DecodeCoords(buf)
+	if err != nil {
+		return nil, err
+	}
+	decoded := make([]float64, c.Dim)
+	for _, coord := range coords {
+		copy(decoded, coord)
+		for i, p := range perm {
+			coord[p] = decoded[i]
+		}
+	}
+	return coords, nil
+}
+
+// EncodeCoordsTagged returns the encoding of an array of coordinates coords
+// prefixed with a single tag byte that records c's Dim and precision, so that
+// the result can be decoded with DecodeCoordsTagged without knowing c. c.Dim
+// must be between 1 and 4, c.Scale must be a power of ten between 1e0 and 1e7,
+// c.Scales must be all zero, c must use degrees rather than radians, and c must
+// use the standard 5 chunk bits, otherwise EncodeCoordsTagged panics.
+func (c Codec) EncodeCoordsTagged(coords [][]float64) []byte {
+	precision, ok := c.precision()
+	if !ok || c.Dim < 1 || c.Dim > 4 || precision > 7 || c.Scales != [MaxScales]float64{} || c.Radians || !c.standardChunkBits() {
+		panic("polyline: codec cannot be tagged")
+	}
+	buf := c.EncodeUint(nil, uint((c.Dim-1)<<3|precision))
+	return c.EncodeCoords(buf, coords)
+}
+
+// EncodeCoordsFramed appends the number of coordinates in coords followed by
+// their encoding to buf, so that the result is self-framing and can be
+// concatenated with other data. It returns the new buf. Use DecodeCoordsFramed
+// to decode the result.
+func (c Codec) EncodeCoordsFramed(buf []byte, coords [][]float64) []byte {
+	c.mustValidate()
+	buf = c.EncodeUint(buf, uint(len(coords)))
+	return c.EncodeCoords(buf, coords)
+}
+
+// DecodeCoordsFramed decodes an array of coordinates encoded with
+// EncodeCoordsFramed from the start of buf. It returns the coordinates, the
+// remaining bytes of buf following the frame, and any error.
+func (c Codec) DecodeCoordsFramed(buf []byte) ([][]float64, []byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, nil, err
+	}
+	n, buf, err := c.DecodeUint(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n > uint(len(buf)/c.Dim) {
+		return nil, nil, errUnterminatedSequence
+	}
+	coords := make([][]float64, n)
+	last := make([]int, c.Dim)
+	for i := range coords {
+		coord := make([]float64, c.Dim)
+		if buf, err = c.decodeCoord(buf, last, coord); err != nil {
+			return nil, nil, err
+		}
+		coords[i] = coord
+	}
+	return coords, buf, nil
+}
+
+// EncodeCoordsDict returns the encoding of an array of coordinates coords
+// relative to a base array of coordinates. If coords and base have the same
+// length then each coordinate is encoded as its difference from the
+// corresponding coordinate in base, otherwise coords are encoded absolutely. A
+// leading flag records which. The same base must be passed to
+// DecodeCoordsDict to decode the result.
+func (c Codec) EncodeCoordsDict(base, coords [][]float64) []byte {
+	c.mustValidate()
+	if len(base) != len(coords) {
+		return c.EncodeCoords(c.EncodeUint(nil, 0), coords)
+	}
+	buf := c.EncodeUint(nil, 1)
+	for i, coord := range coords {
+		for j, x := range coord {
+			buf = c.EncodeInt(buf, c.toInt(j, x)-c.toInt(j, base[i][j]))
+		}
+	}
+	return buf
+}
+
+// DecodeCoordsDict decodes an array of coordinates encoded with
+// EncodeCoordsDict from buf, using the same base that was used to encode them.
+// It returns the coordinates and any error.
+func (c Codec) DecodeCoordsDict(base [][]float64, buf []byte) ([][]float64, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	flag, buf, err := c.DecodeUint(buf)
+	if err != nil {
+		return nil, err
+	}
+	switch flag {
+	case 0:
+		if len(buf) == 0 {
+			return nil, nil
+		}
+		coords, _, err := c.DecodeCoords(buf)
+		return coords, err
+	case 1:
+		coords := make([][]float64, 0, len(base))
+		for len(buf) > 0 {
+			if len(coords) == len(base) {
+				return nil, errCountMismatch
+			}
+			b := base[len(coords)]
+			if len(b) != c.Dim {
+				return nil, errDimensionalMismatch
+			}
+			coord := make([]float64, c.Dim)
+			for j := range coord {
+				var k int
+				k, buf, err = c.DecodeInt(buf)
+				if err != nil {
+					return nil, err
+				}
+				coord[j] = c.toFloat(j, c.toInt(j, b[j])+k)
+			}
+			coords = append(coords, coord)
+		}
+		if len(coords) != len(base) {
+			return nil, errCountMismatch
+		}
+		return coords, nil
+	default:
+		return nil, errInvalidTag
+	}
+}
+
+// EncodeCoordsWithCRC returns the encoding of an array of coordinates coords
+// followed by the CRC-32 (IEEE) checksum of that encoding, itself encoded as an
+// unsigned integer. Use DecodeCoordsVerified to decode the result.
+func (c Codec) EncodeCoordsWithCRC(coords [][]float64) []byte {
+	c.mustValidate()
+	buf := c.EncodeCoords(nil, coords)
+	return c.EncodeUint(buf, uint(crc32.ChecksumIEEE(buf)))
+}
+
+// DecodeCoordsVerified decodes an array of coordinates encoded with
+// EncodeCoordsWithCRC from buf, first verifying the trailing checksum. It
+// returns the coordinates and any error.
+func (c Codec) DecodeCoordsVerified(buf []byte) ([][]float64, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	limit := c.terminatorLimit()
+	if len(buf) == 0 || buf[len(buf)-1] >= limit {
+		return nil, errUnterminatedSequence
+	}
+	i := len(buf) - 1
+	for i > 0 && buf[i-1] >= limit {
+		i--
+	}
+	crc, _, err := c.DecodeUint(buf[i:])
+	if err != nil {
+		return nil, err
+	}
+	if crc != uint(crc32.ChecksumIEEE(buf[:i])) {
+		return nil, errChecksumMismatch
+	}
+	coords, _, err := c.DecodeCoords(buf[:i])
+	return coords, err
+}
+
 // EncodeFlatCoords encodes a one-dimensional array of coordinates to buf. It
 // returns the new buf and any error.
 func (c Codec) EncodeFlatCoords(buf []byte, flatCoords []float64) ([]byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
 	if len(flatCoords)%c.Dim != 0 {
 		return nil, errDimensionalMismatch
 	}
 	last := make([]int, c.Dim)
 	for i, x := range flatCoords {
-		ex := round(c.Scale * x)
 		j := i % c.Dim
-		buf = EncodeInt(buf, ex-last[j])
+		ex := c.toInt(j, x)
+		buf = c.EncodeInt(buf, ex-last[j])
 		last[j] = ex
 	}
 	return buf, nil
@@ -207,14 +1914,233 @@ func DecodeCoords(buf []byte) ([][]float64, []byte, error) {
 	return defaultCodec.DecodeCoords(buf)
 }
 
+// DecodeCoordsN decodes exactly n coordinates from the start of buf using the
+// default codec. It returns the coordinates, the remaining unconsumed bytes of
+// buf, and any error.
+func DecodeCoordsN(buf []byte, n int) ([][]float64, []byte, error) {
+	return defaultCodec.DecodeCoordsN(buf, n)
+}
+
+// Valid returns whether buf contains a valid encoding of an array of
+// coordinates using the default codec.
+func Valid(buf []byte) bool {
+	return defaultCodec.Valid(buf)
+}
+
+// DecodeCoordsTagged decodes an array of coordinates encoded with
+// EncodeCoordsTagged. It returns the codec recorded in the tag, the
+// coordinates, and any error.
+func DecodeCoordsTagged(buf []byte) (Codec, [][]float64, error) {
+	tag, buf, err := DecodeUint(buf)
+	if err != nil {
+		return Codec{}, nil, err
+	}
+	if tag >= 32 {
+		return Codec{}, nil, errInvalidTag
+	}
+	c := Codec{
+		Dim:   int(tag>>3) + 1,
+		Scale: math.Pow10(int(tag & 7)),
+	}
+	coords, _, err := c.DecodeCoords(buf)
+	if err != nil {
+		return Codec{}, nil, err
+	}
+	return c, coords, nil
+}
+
+// DecodeAutoPrecision decodes an array of two-dimensional coordinates from buf,
+// which may be prefixed by its precision encoded as an unsigned integer. A
+// prefix is detected if the first byte encodes 5 or 6 and the remaining bytes
+// encode a whole number of coordinates; otherwise buf is decoded with the
+// default codec. It returns the coordinates, the precision, and any error.
+func DecodeAutoPrecision(buf []byte) ([][]float64, int, error) {
+	if len(buf) > 1 && (buf[0] == 63+5 || buf[0] == 63+6) {
+		if n, err := countInts(buf[1:]); err == nil && n%2 == 0 {
+			precision := int(buf[0] - 63)
+			c := Codec{Dim: 2, Scale: math.Pow10(precision)}
+			coords, _, err := c.DecodeCoords(buf[1:])
+			return coords, precision, err
+		}
+	}
+	coords, _, err := DecodeCoords(buf)
+	return coords, 5, err
+}
+
 // EncodeCoord returns the encoding of an array of coordinates using the default
 // codec.
 func EncodeCoord(coord []float64) []byte {
 	return defaultCodec.EncodeCoord(nil, coord)
 }
 
+// EncodeColumns appends the encoding of coordinates stored column-wise to buf
+// using the default codec. It returns the new buf and any error.
+func EncodeColumns(buf []byte, cols ...[]float64) ([]byte, error) {
+	return defaultCodec.EncodeColumns(buf, cols...)
+}
+
 // EncodeCoords returns the encoding of an array of coordinates using the
 // default codec.
 func EncodeCoords(coords [][]float64) []byte {
 	return defaultCodec.EncodeCoords(nil, coords)
 }
+
+// DecodeCoordsParallel decodes each buffer in bufs using the default codec,
+// distributing the work across workers goroutines. If workers is less than or
+// equal to zero then runtime.GOMAXPROCS(0) workers are used. It returns the
+// coordinates and the error for each buffer, in the same order as bufs.
+func DecodeCoordsParallel(bufs [][]byte, workers int) ([][][]float64, []error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(bufs) {
+		workers = len(bufs)
+	}
+	coordss := make([][][]float64, len(bufs))
+	errs := make([]error, len(bufs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				coordss[i], _, errs[i] = DecodeCoords(bufs[i])
+			}
+		}()
+	}
+	for i := range bufs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return coordss, errs
+}
+
+// DecodeCoordsSafe decodes an array of coordinates from buf, converting any
+// panic during decoding into a returned error. It also returns an error if
+// c.Dim is not positive, which would otherwise cause DecodeCoords to panic or
+// never return. It returns the coordinates and any error.
+func (c Codec) DecodeCoordsSafe(buf []byte) ([][]float64, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if c.Dim < 1 {
+		return nil, errDimensionalMismatch
+	}
+	return recoverCoords(func() ([][]float64, error) {
+		coords, _, err := c.DecodeCoords(buf)
+		return coords, err
+	})
+}
+
+// recoverCoords calls f, converting any panic into a returned error.
+func recoverCoords(f func() ([][]float64, error)) (coords [][]float64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			coords, err = nil, fmt.Errorf("polyline: recovered from %w: %v", errPanic, r)
+		}
+	}()
+	return f()
+}
+
+// DecodeCoordsTrim decodes an array of coordinates from buf using the default
+// codec after trimming leading and trailing ASCII whitespace (space, tab, CR,
+// and LF), such as the trailing newline of a line read from a file. It returns
+// the coordinates and any error.
+func DecodeCoordsTrim(buf []byte) ([][]float64, error) {
+	coords, _, err := DecodeCoords(bytes.Trim(buf, " \t\r\n"))
+	return coords, err
+}
+
+// DecodeRingCoords decodes an array of coordinates from buf using the default
+// codec and ensures that the result is a closed ring by appending a copy of the
+// first coordinate if it differs from the last at the codec's precision. It
+// returns the coordinates and any error.
+func DecodeRingCoords(buf []byte) ([][]float64, error) {
+	coords, _, err := DecodeCoords(buf)
+	if err != nil {
+		return nil, err
+	}
+	first, last := coords[0], coords[len(coords)-1]
+	for i := range first {
+		if round(defaultCodec.Scale*first[i]) != round(defaultCodec.Scale*last[i]) {
+			return append(coords, append([]float64(nil), first...)), nil
+		}
+	}
+	return coords, nil
+}
+
+// CoordsApproxEqual returns whether a and b contain the same number of
+// coordinates with the same dimensionality and every pair of corresponding
+// components differs by at most tol. As with floating point comparison, NaN is
+// not approximately equal to anything, including NaN.
+func CoordsApproxEqual(a, b [][]float64, tol float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, ca := range a {
+		cb := b[i]
+		if len(ca) != len(cb) {
+			return false
+		}
+		for j, x := range ca {
+			if x != cb[j] && !(math.Abs(x-cb[j]) <= tol) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// A Reusable decodes polylines, reusing its internal buffers between calls to
+// reduce allocations when decoding many polylines.
+type Reusable struct {
+	c          Codec
+	last       []int
+	flatCoords []float64
+	coords     [][]float64
+}
+
+// NewReusable returns a new Reusable that decodes polylines with c.
+func NewReusable(c Codec) *Reusable {
+	return &Reusable{
+		c:    c,
+		last: make([]int, c.Dim),
+	}
+}
+
+// Decode decodes an array of coordinates from buf. It returns the coordinates
+// and any error. The returned coordinates are only valid until the next call
+// to Decode, which overwrites them.
+func (r *Reusable) Decode(buf []byte) ([][]float64, error) {
+	if err := r.c.validate(); err != nil {
+		return nil, err
+	}
+	n, err := r.c.countInts(buf)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 || n%r.c.Dim != 0 {
+		return nil, errUnterminatedSequence
+	}
+	if cap(r.flatCoords) < n {
+		r.flatCoords = make([]float64, n)
+	}
+	r.flatCoords = r.flatCoords[:n]
+	if cap(r.coords) < n/r.c.Dim {
+		r.coords = make([][]float64, n/r.c.Dim)
+	}
+	r.coords = r.coords[:n/r.c.Dim]
+	for j := range r.last {
+		r.last[j] = 0
+	}
+	for i := range r.coords {
+		coord := r.flatCoords[i*r.c.Dim : (i+1)*r.c.Dim : (i+1)*r.c.Dim]
+		if buf, err = r.c.decodeCoord(buf, r.last, coord); err != nil {
+			return nil, err
+		}
+		r.coords[i] = coord
+	}
+	return r.coords, nil
+}
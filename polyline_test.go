@@ -1,9 +1,12 @@
 package polyline
 
 import (
+	"errors"
 	"math"
 	"math/rand"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"testing/quick"
 
@@ -95,6 +98,19 @@ func TestInt(t *testing.T) {
 	}
 }
 
+func TestIntLimits(t *testing.T) {
+	const (
+		maxInt = 1<<(strconv.IntSize-1) - 1
+		minInt = -1 << (strconv.IntSize - 1)
+	)
+	for _, i := range []int{maxInt, minInt, math.MaxInt32, math.MinInt32} {
+		got, b, err := DecodeInt(EncodeInt(nil, i))
+		assert.NoError(t, err)
+		assert.Empty(t, b)
+		assert.Equal(t, i, got)
+	}
+}
+
 func TestCoord(t *testing.T) {
 	for _, tc := range []struct {
 		s            string
@@ -121,6 +137,73 @@ func TestCoord(t *testing.T) {
 	}
 }
 
+func TestDeltaInts(t *testing.T) {
+	for _, tc := range []struct {
+		c     Codec
+		delta []int
+		s     string
+	}{
+		{
+			c:     defaultCodec,
+			delta: []int{3850000, -12020000},
+			s:     "_p~iF~ps|U",
+		},
+		{
+			c:     defaultCodec,
+			delta: []int{220000, -75000},
+			s:     "_ulLnnqC",
+		},
+		{
+			c:     Codec{Dim: 3, Scale: 1},
+			delta: []int{1, -1, 0},
+			s:     "A@?",
+		},
+	} {
+		buf := tc.c.EncodeDeltaInts(nil, tc.delta)
+		assert.Equal(t, tc.s, string(buf))
+		got, rest, err := tc.c.DecodeDeltaInts(append(buf, '?'))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.delta, got)
+		assert.Equal(t, "?", string(rest))
+	}
+	_, _, err := defaultCodec.DecodeDeltaInts([]byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestMaxCoordBytes(t *testing.T) {
+	for _, tc := range []struct {
+		c    Codec
+		s    string
+		want int
+	}{
+		{c: defaultCodec, s: "", want: 0},
+		{c: defaultCodec, s: "??", want: 2},
+		{c: defaultCodec, s: "_p~iF~ps|U_ulLnnqC_mqNvxq`@", want: 10},
+		{c: defaultCodec, s: "_ulLnnqC_mqNvxq`@", want: 9},
+		{c: Codec{Dim: 3, Scale: 1}, s: "A@?_ibE??", want: 6},
+		{c: Codec{Dim: 2, Scale: 1, ChunkBits: 6}, s: string([]byte{63, 127, 64}), want: 3},
+	} {
+		got, err := tc.c.MaxCoordBytes([]byte(tc.s))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+	for _, tc := range []struct {
+		c   Codec
+		s   string
+		err error
+	}{
+		{c: defaultCodec, s: "_p~iF", err: errDimensionalMismatch},
+		{c: defaultCodec, s: "_p~iF~ps|U_", err: errUnterminatedSequence},
+		{c: defaultCodec, s: "_p~iF~ps|U!", err: errInvalidByte},
+		{c: Codec{Dim: 3, Scale: 1}, s: "??", err: errDimensionalMismatch},
+	} {
+		_, err := tc.c.MaxCoordBytes([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+		_, err = tc.c.CountCoords([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+	}
+}
+
 func TestCoords(t *testing.T) {
 	for _, tc := range []struct {
 		cs [][]float64
@@ -159,6 +242,179 @@ func TestFlatCoords(t *testing.T) {
 	}
 }
 
+func TestScales(t *testing.T) {
+	c := Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{1e5, 1e5, 1e2}}
+	cs := [][]float64{{38.5, -120.2, 100.25}, {40.7, -120.95, 99.5}}
+	s := "_p~iF~ps|UqqR_ulLnnqCtC"
+	assert.Equal(t, s, string(c.EncodeCoords(nil, cs)))
+	got, _, err := c.DecodeCoords([]byte(s))
+	assert.NoError(t, err)
+	assert.Equal(t, cs, got)
+	assert.Equal(t, "_p~iF~ps|UqqR", string(c.EncodeCoord(nil, cs[0])))
+	coord, b, err := c.DecodeCoord([]byte(s))
+	assert.NoError(t, err)
+	assert.Equal(t, cs[0], coord)
+	assert.Equal(t, "_ulLnnqCtC", string(b))
+	fcs := []float64{38.5, -120.2, 100.25, 40.7, -120.95, 99.5}
+	gotBytes, err := c.EncodeFlatCoords(nil, fcs)
+	assert.NoError(t, err)
+	assert.Equal(t, s, string(gotBytes))
+	gotFCS, _, err := c.DecodeFlatCoords(nil, []byte(s))
+	assert.NoError(t, err)
+	assert.Equal(t, fcs, gotFCS)
+
+	assert.True(t, c == Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{1e5, 1e5, 1e2}})
+	assert.Equal(t, s, string(Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{0, 0, 1e2}}.EncodeCoords(nil, cs)))
+	assert.Equal(t, c.EncodedLen(cs), len(s))
+	gotCols, err := c.EncodeColumns(nil, []float64{38.5, 40.7}, []float64{-120.2, -120.95}, []float64{100.25, 99.5})
+	assert.NoError(t, err)
+	assert.Equal(t, s, string(gotCols))
+	channels, err := c.DecodePerDimension([]byte(s))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{38.5, 40.7}, {-120.2, -120.95}, {100.25, 99.5}}, channels)
+	gotPermuted, err := c.EncodeCoordsPermuted(nil, cs, []int{0, 1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, s, string(gotPermuted))
+	got, _, err = c.DecodeCoordsWrapped(c.EncodeCoordsUnwrapped(nil, cs))
+	assert.NoError(t, err)
+	assert.Equal(t, cs, got)
+	got, err = c.DecodeCoordsDict(cs[:1], c.EncodeCoordsDict(cs[:1], cs))
+	assert.NoError(t, err)
+	assert.Equal(t, cs, got)
+	got, err = NewReusable(c).Decode([]byte(s))
+	assert.NoError(t, err)
+	assert.Equal(t, cs, got)
+	d := NewDecoder(strings.NewReader(s), c)
+	coord, err = d.DecodeCoord()
+	assert.NoError(t, err)
+	assert.Equal(t, cs[0], coord)
+	index, _, err := c.DecodeNearestVertex([]byte(s), cs[1])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, index)
+
+	c = Codec{Dim: 2, Scale: 1e5, Scales: [MaxScales]float64{1e5, 1e5, 1e2}}
+	_, _, err = c.DecodeCoord([]byte(s))
+	assert.Equal(t, errDimensionalMismatch, err)
+	_, _, err = c.DecodeCoords([]byte(s))
+	assert.Equal(t, errDimensionalMismatch, err)
+	_, err = c.EncodeFlatCoords(nil, fcs)
+	assert.Equal(t, errDimensionalMismatch, err)
+	_, _, err = c.DecodeFlatCoords(nil, []byte(s))
+	assert.Equal(t, errDimensionalMismatch, err)
+	_, err = NewDecoder(strings.NewReader(s), c).DecodeCoord()
+	assert.Equal(t, errDimensionalMismatch, err)
+	_, _, err = c.DecodeNearestVertex([]byte(s), cs[0][:2])
+	assert.Equal(t, errDimensionalMismatch, err)
+	_, err = c.DecodeCoordsDict(nil, []byte(s))
+	assert.Equal(t, errDimensionalMismatch, err)
+	assert.Panics(t, func() { c.EncodeCoord(nil, cs[0][:2]) })
+	assert.Panics(t, func() { c.EncodeCoords(nil, nil) })
+	assert.Panics(t, func() { c.EncodeCoordsQuantized(nil, nil, 1e4) })
+	assert.Panics(t, func() { c.EncodeCoordsUnwrapped(nil, nil) })
+}
+
+func TestInvalidScales(t *testing.T) {
+	c := Codec{Dim: 2, Scale: 1e5, Scales: [MaxScales]float64{1e5, 1e5, 1e2}}
+	buf := []byte("_p~iF~ps|U_ulLnnqC_p~iF~ps|U")
+	cs := [][]float64{{38.5, -120.2}, {40.7, -120.95}}
+	for i, f := range []func() error{
+		func() error { _, err := c.Diff(buf, buf); return err },
+		func() error { _, err := c.ClipToBounds(buf, 0, -180, 90, 180); return err },
+		func() error { _, err := c.DecodeLength(buf); return err },
+		func() error { _, _, err := c.DecodeNearestVertex(buf, cs[0]); return err },
+		func() error { _, err := c.ConcatSimplify([][]byte{buf}, 0); return err },
+		func() error { _, err := c.CountCoords(buf); return err },
+		func() error { _, err := c.MaxCoordBytes(buf); return err },
+		func() error { _, _, err := c.DecodeCoord(buf); return err },
+		func() error { _, _, err := c.DecodeDeltaInts(buf); return err },
+		func() error { _, _, err := c.DecodeCoords(buf); return err },
+		func() error { _, _, err := c.DecodeCoordsInto(nil, buf); return err },
+		func() error { _, _, err := c.DecodeCoordsN(buf, 1); return err },
+		func() error { _, _, err := c.DecodeCoordsHint(buf, 2); return err },
+		func() error { _, err := c.DecodeCoordsExpect(buf, 3); return err },
+		func() error { _, _, err := c.DecodeCoordsArena(buf); return err },
+		func() error { _, _, err := c.DecodeCoordsPartial(buf); return err },
+		func() error { _, err := c.DecodeCoords32(buf); return err },
+		func() error { _, _, err := c.DecodeIntBounds(buf); return err },
+		func() error { _, err := c.DecodeCoordsFrom([]int{0, 0}, buf); return err },
+		func() error { _, _, err := c.DecodeCoordsMaxDelta(buf); return err },
+		func() error { return c.DecodeWindow(buf, 2, func([][]float64) error { return nil }) },
+		func() error { _, _, err := c.DecodeCoordsWithAltCheck(buf, 1); return err },
+		func() error { _, err := c.DecodeRing(buf, 2, nil); return err },
+		func() error { _, _, err := c.DecodeCoordsWithStats(buf); return err },
+		func() error { _, _, err := c.DecodeCoordsResume(buf, Cursor{}, 1); return err },
+		func() error { _, err := c.IsClosed(buf); return err },
+		func() error { _, err := c.RotateRing(buf, 1); return err },
+		func() error { _, _, err := c.DecodeFlatCoords(nil, buf); return err },
+		func() error { _, err := c.DecodeFlatInts(buf); return err },
+		func() error { _, err := c.DecodeFlatInt32(nil, buf); return err },
+		func() error { _, err := c.Deltas(buf); return err },
+		func() error { _, err := c.EncodeCoordsFixed(make([]byte, 64), cs); return err },
+		func() error { _, err := c.EncodeCoordsChecked(nil, cs); return err },
+		func() error { _, err := c.EncodeCoordsFrom(nil, []int{0, 0}, cs); return err },
+		func() error { _, err := c.EncodeColumns(nil, []float64{1}, []float64{2}); return err },
+		func() error { _, err := c.DecodePerDimension(buf); return err },
+		func() error { _, err := c.EncodePerDimension(nil, [][]float64{{1}, {2}}); return err },
+		func() error { _, _, err := c.AppendRaw(nil, buf, []int{0, 0}); return err },
+		func() error { _, _, err := c.DecodeCoordsWrapped(buf); return err },
+		func() error { _, err := c.EncodeCoordsPermuted(nil, cs, []int{1, 0}); return err },
+		func() error { _, err := c.DecodeCoordsPermuted(buf, []int{1, 0}); return err },
+		func() error { _, _, err := c.DecodeCoordsFramed(buf); return err },
+		func() error { _, err := c.DecodeCoordsDict(cs, buf); return err },
+		func() error { _, err := c.DecodeCoordsVerified(buf); return err },
+		func() error { _, err := c.EncodeFlatCoords(nil, []float64{1, 2}); return err },
+		func() error { _, err := c.DecodeCoordsSafe(buf); return err },
+	} {
+		assert.Equal(t, errDimensionalMismatch, f(), "%d", i)
+	}
+	for i, f := range []func(){
+		func() { c.EncodeBBox(nil, 0, 0, 1, 1) },
+		func() { c.EncodeCoord(nil, cs[0]) },
+		func() { c.EncodeDeltaInts(nil, []int{1, 2}) },
+		func() { c.EncodeCoords(nil, cs) },
+		func() { c.EncodedLen(cs) },
+		func() { c.EncodedLenFlat([]float64{1, 2}) },
+		func() { c.EncodeCoordsQuantized(nil, cs, 1e4) },
+		func() { c.EncodeCoordsUnwrapped(nil, cs) },
+		func() { c.EncodeCoordsTagged(cs) },
+		func() { c.EncodeCoordsFramed(nil, cs) },
+		func() { c.EncodeCoordsDict(cs, cs) },
+		func() { c.EncodeCoordsWithCRC(cs) },
+	} {
+		assert.Panics(t, f, "%d", i)
+	}
+	assert.False(t, c.Valid(buf))
+	assert.False(t, c.IsStandard())
+}
+
+func TestDecodeFlatCoordsSplit(t *testing.T) {
+	for _, tc := range []struct {
+		c      Codec
+		coords [][]float64
+	}{
+		{
+			c:      defaultCodec,
+			coords: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			c:      Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{1e5, 1e5, 1e2}},
+			coords: [][]float64{{38.5, -120.2, 100.25}, {40.7, -120.95, 99.5}, {43.252, -126.453, -3}},
+		},
+	} {
+		buf := tc.c.EncodeCoords(nil, tc.coords)
+		want, _, err := tc.c.DecodeFlatCoords(nil, buf)
+		assert.NoError(t, err)
+		for i := 1; i < len(tc.coords); i++ {
+			split := len(tc.c.EncodeCoords(nil, tc.coords[:i]))
+			got, _, err := tc.c.DecodeFlatCoords(nil, buf[:split])
+			assert.NoError(t, err)
+			got, _, err = tc.c.DecodeFlatCoords(got, buf[split:])
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+		}
+	}
+}
+
 func TestDecodeFlatCoordsErrors(t *testing.T) {
 	for _, tc := range []struct {
 		fcs []float64
@@ -296,3 +552,1949 @@ func TestFlatCoordsQuick(t *testing.T) {
 	}
 	assert.NoError(t, quick.Check(f, nil))
 }
+
+func TestDeltas(t *testing.T) {
+	for _, tc := range []struct {
+		c   Codec
+		s   string
+		ds  [][]int
+		err error
+	}{
+		{
+			c:  Codec{Dim: 2, Scale: 1e5},
+			s:  "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			ds: [][]int{{3850000, -12020000}, {220000, -75000}, {255200, -550300}},
+		},
+		{
+			c:  Codec{Dim: 1, Scale: 1e5},
+			s:  "_p~iF~ps|U",
+			ds: [][]int{{3850000}, {-12020000}},
+		},
+		{
+			c:   Codec{Dim: 2, Scale: 1e5},
+			s:   "_p~iF~ps|U_ulL",
+			err: errUnterminatedSequence,
+		},
+	} {
+		got, err := tc.c.Deltas([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+		assert.Equal(t, tc.ds, got)
+	}
+}
+
+func TestCoordsTagged(t *testing.T) {
+	for _, tc := range []struct {
+		c  Codec
+		cs [][]float64
+		s  string
+	}{
+		{
+			c:  Codec{Dim: 2, Scale: 1e5},
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			s:  "L_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			c:  Codec{Dim: 2, Scale: 1e6},
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			s:  "M_izlhA~rlgdF_{geC~ywl@_kwzCn`{nI",
+		},
+		{
+			c:  Codec{Dim: 1, Scale: 1},
+			cs: [][]float64{{1}, {2}},
+			s:  "?AA",
+		},
+	} {
+		assert.Equal(t, []byte(tc.s), tc.c.EncodeCoordsTagged(tc.cs))
+		gotCodec, got, err := DecodeCoordsTagged([]byte(tc.s))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.c, gotCodec)
+		assert.Equal(t, tc.cs, got)
+	}
+}
+
+func TestDecodeCoordsTaggedErrors(t *testing.T) {
+	for _, tc := range []struct {
+		s   string
+		err error
+	}{
+		{s: "", err: errUnterminatedSequence},
+		{s: "_@?", err: errInvalidTag},
+		{s: "L_p~iF", err: errUnterminatedSequence},
+	} {
+		_, _, err := DecodeCoordsTagged([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+	}
+}
+
+func TestEncodeCoordsTaggedPanics(t *testing.T) {
+	for _, c := range []Codec{
+		{Dim: 0, Scale: 1e5},
+		{Dim: 5, Scale: 1e5},
+		{Dim: 2, Scale: 99999},
+		{Dim: 2, Scale: 1e8},
+		{Dim: 2, Scale: 1e5, Scales: [MaxScales]float64{1e5, 1e6}},
+		{Dim: 2, Scale: 1e5, Radians: true},
+	} {
+		assert.Panics(t, func() { c.EncodeCoordsTagged(nil) })
+	}
+}
+
+func TestDecodeCoordsParallel(t *testing.T) {
+	bufs := [][]byte{
+		[]byte("_p~iF~ps|U_ulLnnqC_mqNvxq`@"),
+		[]byte("_p~iF"),
+		[]byte("_p~iF~ps|U"),
+	}
+	for _, workers := range []int{-1, 0, 1, 2, 8} {
+		gotCoords, gotErrs := DecodeCoordsParallel(bufs, workers)
+		assert.Equal(t, [][][]float64{
+			{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			nil,
+			{{38.5, -120.2}},
+		}, gotCoords)
+		assert.Equal(t, []error{nil, errUnterminatedSequence, nil}, gotErrs)
+	}
+}
+
+func TestDecodeCoords32(t *testing.T) {
+	got, err := defaultCodec.DecodeCoords32([]byte("_p~iF~ps|U_ulLnnqC_mqNvxq`@"))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float32{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}, got)
+	_, err = defaultCodec.DecodeCoords32([]byte("_p~iF~ps|U_ulL"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeCoords32Quick(t *testing.T) {
+	f := func(qc QuickCoords) bool {
+		cs, err := defaultCodec.DecodeCoords32(EncodeCoords([][]float64(qc)))
+		if err != nil || len(cs) != len(qc) {
+			return false
+		}
+		for i, c := range cs {
+			if !float64ArrayWithin([]float64{float64(c[0]), float64(c[1])}, qc[i], 1.5e-5) {
+				return false
+			}
+		}
+		return true
+	}
+	assert.NoError(t, quick.Check(f, nil))
+}
+
+func TestDecodeIntBounds(t *testing.T) {
+	for _, tc := range []struct {
+		s   string
+		min []int
+		max []int
+		err error
+	}{
+		{
+			s:   "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			min: []int{3850000, -12645300},
+			max: []int{4325200, -12020000},
+		},
+		{
+			s:   "_p~iF~ps|U",
+			min: []int{3850000, -12020000},
+			max: []int{3850000, -12020000},
+		},
+		{
+			s:   "",
+			err: errUnterminatedSequence,
+		},
+		{
+			s:   "_p~iF~ps|U_ulL",
+			err: errUnterminatedSequence,
+		},
+	} {
+		gotMin, gotMax, err := defaultCodec.DecodeIntBounds([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+		assert.Equal(t, tc.min, gotMin)
+		assert.Equal(t, tc.max, gotMax)
+	}
+}
+
+func TestDecodeRingCoords(t *testing.T) {
+	for _, tc := range []struct {
+		s   string
+		cs  [][]float64
+		err error
+	}{
+		{
+			s:  "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}, {38.5, -120.2}},
+		},
+		{
+			s:  "_p~iF~ps|U_ulLnnqC~tlLonqC",
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {38.5, -120.2}},
+		},
+		{
+			s:  "_p~iF~ps|U",
+			cs: [][]float64{{38.5, -120.2}},
+		},
+		{
+			s:   "_p~iF",
+			err: errUnterminatedSequence,
+		},
+	} {
+		got, err := DecodeRingCoords([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+		assert.Equal(t, tc.cs, got)
+	}
+}
+
+func TestIsStandard(t *testing.T) {
+	for _, tc := range []struct {
+		c    Codec
+		want bool
+	}{
+		{c: Codec{Dim: 2, Scale: 1e5}, want: true},
+		{c: Codec{Dim: 2, Scale: 1e6}, want: true},
+		{c: Codec{Dim: 3, Scale: 1e5}, want: true},
+		{c: Codec{Dim: 2, Scale: 1}, want: true},
+		{c: Codec{Dim: 1, Scale: 1e5}, want: false},
+		{c: Codec{Dim: 4, Scale: 1e5}, want: false},
+		{c: Codec{Dim: 2, Scale: 99999}, want: false},
+		{c: Codec{Dim: 2, Scale: 1e5 + 1e-6}, want: false},
+		{c: Codec{Dim: 2, Scale: 1e-5}, want: false},
+		{c: Codec{Dim: 2, Scale: 0}, want: false},
+		{c: Codec{Dim: 2, Scale: 1e5, ChunkBits: 5}, want: true},
+		{c: Codec{Dim: 2, Scale: 1e5, ChunkBits: 4}, want: false},
+		{c: Codec{Dim: 2, Scale: 1e5, ChunkBits: 7}, want: false},
+		{c: Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{1e5, 1e5, 1e2}}, want: false},
+	} {
+		assert.Equal(t, tc.want, tc.c.IsStandard(), "%+v", tc.c)
+	}
+}
+
+func TestDecodeFlatInt32(t *testing.T) {
+	for _, tc := range []struct {
+		c   Codec
+		dst []int32
+		s   string
+		is  []int32
+		err error
+	}{
+		{
+			c:  Codec{Dim: 2, Scale: 1e5},
+			s:  "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			is: []int32{3850000, -12020000, 4070000, -12095000, 4325200, -12645300},
+		},
+		{
+			c:   Codec{Dim: 2, Scale: 1e5},
+			dst: []int32{1, 2},
+			s:   "_p~iF~ps|U",
+			is:  []int32{1, 2, 3850000, -12020000},
+		},
+		{
+			c:  Codec{Dim: 1, Scale: 1e5},
+			s:  "",
+			is: nil,
+		},
+		{
+			c:   Codec{Dim: 2, Scale: 1e5},
+			dst: []int32{1},
+			s:   "_p~iF~ps|U",
+			err: errDimensionalMismatch,
+		},
+		{
+			c:   Codec{Dim: 2, Scale: 1e5},
+			s:   "_p~iF~ps|U_ulL",
+			err: errUnterminatedSequence,
+		},
+		{
+			c:   Codec{Dim: 1, Scale: 1e5},
+			s:   string(EncodeInt(nil, math.MaxInt32+1)),
+			err: errOverflow,
+		},
+		{
+			c:   Codec{Dim: 1, Scale: 1e5},
+			s:   string(EncodeInt(EncodeInt(nil, math.MinInt32), -1)),
+			err: errOverflow,
+		},
+	} {
+		got, err := tc.c.DecodeFlatInt32(tc.dst, []byte(tc.s))
+		assert.Equal(t, tc.err, err)
+		assert.Equal(t, tc.is, got)
+	}
+}
+
+func TestCoordsFrom(t *testing.T) {
+	for _, tc := range []struct {
+		origin []int
+		cs     [][]float64
+		s      string
+	}{
+		{
+			origin: []int{0, 0},
+			cs:     [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			s:      "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			origin: []int{3850000, -12020000},
+			cs:     [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			s:      "??_ulLnnqC_mqNvxq`@",
+		},
+	} {
+		gotBytes, err := defaultCodec.EncodeCoordsFrom(nil, tc.origin, tc.cs)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(tc.s), gotBytes)
+		got, err := defaultCodec.DecodeCoordsFrom(tc.origin, []byte(tc.s))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.cs, got)
+	}
+}
+
+func TestCoordsFromErrors(t *testing.T) {
+	_, err := defaultCodec.EncodeCoordsFrom(nil, []int{0}, nil)
+	assert.Equal(t, errDimensionalMismatch, err)
+	_, err = defaultCodec.DecodeCoordsFrom([]int{0, 0, 0}, []byte("??"))
+	assert.Equal(t, errDimensionalMismatch, err)
+	_, err = defaultCodec.DecodeCoordsFrom([]int{0, 0}, []byte("?"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestCoordsApproxEqual(t *testing.T) {
+	for _, tc := range []struct {
+		a    [][]float64
+		b    [][]float64
+		tol  float64
+		want bool
+	}{
+		{a: nil, b: nil, want: true},
+		{a: [][]float64{{1, 2}}, b: [][]float64{{1, 2}}, want: true},
+		{a: [][]float64{{1, 2}}, b: [][]float64{{1.000001, 1.999999}}, tol: 1e-5, want: true},
+		{a: [][]float64{{1, 2}}, b: [][]float64{{1.0001, 2}}, tol: 1e-5, want: false},
+		{a: [][]float64{{1, 2}}, b: [][]float64{{1, 2}, {3, 4}}, tol: 1, want: false},
+		{a: [][]float64{{1, 2}}, b: [][]float64{{1, 2, 3}}, tol: 1, want: false},
+		{a: [][]float64{{math.NaN(), 2}}, b: [][]float64{{math.NaN(), 2}}, tol: 1, want: false},
+		{a: [][]float64{{math.Inf(1), 2}}, b: [][]float64{{math.Inf(1), 2}}, tol: 1, want: true},
+		{a: [][]float64{{math.Inf(1), 2}}, b: [][]float64{{math.Inf(-1), 2}}, tol: 1, want: false},
+	} {
+		assert.Equal(t, tc.want, CoordsApproxEqual(tc.a, tc.b, tc.tol))
+		assert.Equal(t, tc.want, CoordsApproxEqual(tc.b, tc.a, tc.tol))
+	}
+}
+
+func TestEncodedLen(t *testing.T) {
+	for _, tc := range []struct {
+		c  Codec
+		cs [][]float64
+	}{
+		{
+			c: defaultCodec,
+		},
+		{
+			c:  defaultCodec,
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			c:  defaultCodec,
+			cs: [][]float64{{0, 0}, {0.00015, -0.00016}, {0.00031, -0.00032}},
+		},
+		{
+			c:  Codec{Dim: 3, Scale: 1, ChunkBits: 3},
+			cs: [][]float64{{1, 2, 3}, {-100, 200, -300}, {1e9, -1e9, 0}},
+		},
+		{
+			c:  Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{1e5, 1e5, 1e2}},
+			cs: [][]float64{{38.5, -120.2, 100.25}, {40.7, -120.95, 99.5}},
+		},
+		{
+			c:  Codec{Dim: 1, Scale: 1},
+			cs: [][]float64{{1 << 40}, {-1 << 40}},
+		},
+		{
+			c:  defaultCodec,
+			cs: benchmarkCoords(1000),
+		},
+	} {
+		assert.Equal(t, len(tc.c.EncodeCoords(nil, tc.cs)), tc.c.EncodedLen(tc.cs))
+		var fcs []float64
+		for _, coord := range tc.cs {
+			fcs = append(fcs, coord...)
+		}
+		buf, err := tc.c.EncodeFlatCoords(nil, fcs)
+		assert.NoError(t, err)
+		assert.Equal(t, len(buf), tc.c.EncodedLenFlat(fcs))
+	}
+}
+
+func TestEncodedLenQuick(t *testing.T) {
+	f := func(qc QuickCoords) bool {
+		return defaultCodec.EncodedLen(qc) == len(EncodeCoords(qc))
+	}
+	assert.NoError(t, quick.Check(f, nil))
+}
+
+func TestEncodeCoordsFixed(t *testing.T) {
+	for _, tc := range []struct {
+		c  Codec
+		cs [][]float64
+	}{
+		{
+			c:  defaultCodec,
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			c:  Codec{Dim: 3, Scale: 1, ChunkBits: 3},
+			cs: [][]float64{{1, 2, 3}, {-100, 200, -300}},
+		},
+		{
+			c:  Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{1e5, 1e5, 1e2}},
+			cs: [][]float64{{38.5, -120.2, 100.25}, {40.7, -120.95, 99.5}},
+		},
+		{
+			c:  defaultCodec,
+			cs: nil,
+		},
+	} {
+		want := tc.c.EncodeCoords(nil, tc.cs)
+		dst := make([]byte, len(want)+4)
+		n, err := tc.c.EncodeCoordsFixed(dst, tc.cs)
+		assert.NoError(t, err)
+		assert.Equal(t, string(want), string(dst[:n]))
+		n, err = tc.c.EncodeCoordsFixed(dst[:tc.c.EncodedLen(tc.cs)], tc.cs)
+		assert.NoError(t, err)
+		assert.Equal(t, len(want), n)
+		assert.Equal(t, string(want), string(dst[:n]))
+		if len(want) > 0 {
+			_, err = tc.c.EncodeCoordsFixed(dst[:len(want)-1], tc.cs)
+			assert.Equal(t, errBufferTooSmall, err)
+		}
+	}
+}
+
+func TestEncodeCoordsFixedAllocs(t *testing.T) {
+	coords := benchmarkCoords(100)
+	dst := make([]byte, 2*MaxEncodedLen*len(coords))
+	allocs := testing.AllocsPerRun(10, func() {
+		if _, err := defaultCodec.EncodeCoordsFixed(dst, coords); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func TestEncodeCoordsQuantized(t *testing.T) {
+	for _, tc := range []struct {
+		c         Codec
+		cs        [][]float64
+		gridScale float64
+		want      [][]float64
+	}{
+		{
+			c:         defaultCodec,
+			cs:        [][]float64{{38.51234, -120.21234}, {40.70049, -120.95051}},
+			gridScale: 1e3,
+			want:      [][]float64{{38.512, -120.212}, {40.7, -120.951}},
+		},
+		{
+			c:         defaultCodec,
+			cs:        [][]float64{{38.5, -120.2}, {40.7, -120.95}},
+			gridScale: 1e5,
+			want:      [][]float64{{38.5, -120.2}, {40.7, -120.95}},
+		},
+		{
+			c:         Codec{Dim: 1, Scale: 1},
+			cs:        [][]float64{{12}, {-12}, {15}},
+			gridScale: 0.1,
+			want:      [][]float64{{10}, {-10}, {20}},
+		},
+	} {
+		buf := tc.c.EncodeCoordsQuantized(nil, tc.cs, tc.gridScale)
+		assert.Equal(t, string(tc.c.EncodeCoords(nil, tc.want)), string(buf))
+	}
+}
+
+func TestEncodeCoordsQuantizedSmaller(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	coords := make([][]float64, 1000)
+	for i := range coords {
+		coords[i] = []float64{45 + 0.0004*(r.Float64()-0.5), 5 + 0.0004*(r.Float64()-0.5)}
+	}
+	full := defaultCodec.EncodeCoords(nil, coords)
+	quantized := defaultCodec.EncodeCoordsQuantized(nil, coords, 1e3)
+	assert.True(t, len(quantized) < 3*len(full)/4)
+}
+
+func TestEncodeCoordsQuantizedPanics(t *testing.T) {
+	cs := [][]float64{{38.5, -120.2}}
+	for _, gridScale := range []float64{0, -1e3, math.NaN()} {
+		assert.Panics(t, func() { defaultCodec.EncodeCoordsQuantized(nil, cs, gridScale) })
+	}
+}
+
+func TestEncodeColumns(t *testing.T) {
+	for _, tc := range []struct {
+		cols [][]float64
+		s    string
+		err  error
+	}{
+		{
+			cols: [][]float64{{38.5, 40.7, 43.252}, {-120.2, -120.95, -126.453}},
+			s:    "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			cols: [][]float64{{}, {}},
+			s:    "",
+		},
+		{
+			cols: [][]float64{{38.5, 40.7, 43.252}},
+			err:  errDimensionalMismatch,
+		},
+		{
+			cols: [][]float64{{38.5, 40.7, 43.252}, {-120.2, -120.95}},
+			err:  errLengthMismatch,
+		},
+	} {
+		got, err := EncodeColumns(nil, tc.cols...)
+		assert.Equal(t, tc.err, err)
+		assert.Equal(t, tc.s, string(got))
+	}
+	_, err := Codec{Dim: 0, Scale: 1}.EncodeColumns(nil)
+	assert.Equal(t, errDimensionalMismatch, err)
+	_, err = Codec{Dim: 0, Scale: 1}.EncodePerDimension(nil, nil)
+	assert.Equal(t, errDimensionalMismatch, err)
+	_, err = EncodeColumns(nil)
+	assert.Equal(t, errDimensionalMismatch, err)
+}
+
+func TestIsClosed(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want bool
+		err  error
+	}{
+		{s: "_p~iF~ps|U_ulLnnqC_mqNvxq`@", want: false},
+		{s: "_p~iF~ps|U_ulLnnqC~tlLonqC", want: true},
+		{s: "_p~iF~ps|U??", want: true},
+		{s: "_p~iF~ps|U", want: false},
+		{s: "", err: errUnterminatedSequence},
+		{s: "_p~iF~ps|U_ulL", err: errUnterminatedSequence},
+	} {
+		got, err := defaultCodec.IsClosed([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestDecodeOverflow(t *testing.T) {
+	ss := []string{strings.Repeat("~", 15) + "?", strings.Repeat("_", 15) + "@"}
+	if strconv.IntSize == 64 {
+		ss = append(ss, "~~~~~~~~~~~~O", "~~~~~~~~~~~~~?")
+	}
+	for _, s := range ss {
+		var err error
+		_, _, err = DecodeUint([]byte(s))
+		assert.Equal(t, errOverflow, err)
+		_, _, err = DecodeInt([]byte(s))
+		assert.Equal(t, errOverflow, err)
+		_, _, err = DecodeUint64([]byte(s))
+		assert.Equal(t, errOverflow, err)
+		_, _, err = DecodeCoords([]byte(s + s))
+		assert.Equal(t, errOverflow, err)
+		_, err = NewDecoder(strings.NewReader(s+s), defaultCodec).DecodeCoord()
+		assert.Equal(t, errOverflow, err)
+	}
+	c := Codec{Dim: 1, Scale: 1, ChunkBits: 4}
+	_, _, err := c.DecodeUint([]byte(strings.Repeat("^", 20) + "?"))
+	assert.Equal(t, errOverflow, err)
+	_, err = NewDecoder(strings.NewReader(strings.Repeat("^", 20)+"?"), c).DecodeCoord()
+	assert.Equal(t, errOverflow, err)
+}
+
+func TestCountCoords(t *testing.T) {
+	for _, tc := range []struct {
+		c    Codec
+		s    string
+		want int
+		err  error
+	}{
+		{c: defaultCodec, s: "", want: 0},
+		{c: defaultCodec, s: "_p~iF~ps|U_ulLnnqC_mqNvxq`@", want: 3},
+		{c: Codec{Dim: 3, Scale: 1}, s: "??????", want: 2},
+		{c: Codec{Dim: 1, Scale: 1, ChunkBits: 3}, s: "NFF", want: 2},
+		{c: defaultCodec, s: "_p~iF~ps|U_ulL", err: errDimensionalMismatch},
+		{c: defaultCodec, s: "_p~iF~ps|U_", err: errUnterminatedSequence},
+		{c: defaultCodec, s: "_p~iF~ps|U>", err: errInvalidByte},
+	} {
+		got, err := tc.c.CountCoords([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, tc := range []struct {
+		c    Codec
+		s    string
+		want bool
+	}{
+		{c: defaultCodec, s: "_p~iF~ps|U_ulLnnqC_mqNvxq`@", want: true},
+		{c: defaultCodec, s: "??", want: true},
+		{c: defaultCodec, s: "", want: false},
+		{c: defaultCodec, s: "_p~iF", want: false},
+		{c: defaultCodec, s: "_p~iF~ps|U_", want: false},
+		{c: defaultCodec, s: "_p~iF~ps|U>", want: false},
+		{c: defaultCodec, s: strings.Repeat("~", 15) + "??", want: false},
+		{c: Codec{Dim: 3, Scale: 1}, s: "???", want: true},
+		{c: Codec{Dim: 3, Scale: 1}, s: "??", want: false},
+		{c: Codec{Dim: 1, Scale: 1, ChunkBits: 3}, s: "NF", want: true},
+		{c: Codec{Dim: 1, Scale: 1, ChunkBits: 3}, s: "N", want: false},
+	} {
+		assert.Equal(t, tc.want, tc.c.Valid([]byte(tc.s)), tc.s)
+		_, _, err := tc.c.DecodeCoords([]byte(tc.s))
+		assert.Equal(t, tc.want, err == nil, tc.s)
+	}
+	assert.True(t, Valid([]byte("_p~iF~ps|U")))
+	assert.False(t, Valid(nil))
+}
+
+func TestValidAllocs(t *testing.T) {
+	buf := EncodeCoords(benchmarkCoords(100))
+	allocs := testing.AllocsPerRun(10, func() {
+		if !Valid(buf) {
+			t.Fatal("not valid")
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func TestNewCodec(t *testing.T) {
+	for _, tc := range []struct {
+		dim       int
+		precision int
+		want      Codec
+	}{
+		{dim: 2, precision: Polyline5, want: defaultCodec},
+		{dim: 2, precision: Polyline6, want: Codec{Dim: 2, Scale: 1e6}},
+		{dim: 3, precision: 0, want: Codec{Dim: 3, Scale: 1}},
+	} {
+		c := NewCodec(tc.dim, tc.precision)
+		assert.Equal(t, tc.want, c)
+		assert.True(t, c.IsStandard())
+	}
+	assert.Equal(t, "_izlhA~rlgdF", string(NewCodec(2, Polyline6).EncodeCoords(nil, [][]float64{{38.5, -120.2}})))
+	assert.Panics(t, func() { NewCodec(2, -1) })
+}
+
+func TestInt64(t *testing.T) {
+	for _, tc := range []struct {
+		i int64
+		s string
+	}{
+		{i: 0, s: "?"},
+		{i: 3850000, s: "_p~iF"},
+		{i: -12020000, s: "~ps|U"},
+		{i: 1 << 40, s: "________A"},
+		{i: math.MaxInt64, s: "}~~~~~~~~~~~N"},
+		{i: math.MinInt64, s: "~~~~~~~~~~~~N"},
+	} {
+		got, b, err := DecodeInt64([]byte(tc.s))
+		assert.NoError(t, err)
+		assert.Empty(t, b)
+		assert.Equal(t, tc.i, got)
+		assert.Equal(t, []byte(tc.s), EncodeInt64(nil, tc.i))
+	}
+}
+
+func TestUint64(t *testing.T) {
+	for _, tc := range []struct {
+		u uint64
+		s string
+	}{
+		{u: 0, s: "?"},
+		{u: 174, s: "mD"},
+		{u: math.MaxUint64, s: "~~~~~~~~~~~~N"},
+	} {
+		got, b, err := DecodeUint64([]byte(tc.s))
+		assert.NoError(t, err)
+		assert.Empty(t, b)
+		assert.Equal(t, tc.u, got)
+		assert.Equal(t, []byte(tc.s), EncodeUint64(nil, tc.u))
+	}
+}
+
+func TestDecodeCoordsInto(t *testing.T) {
+	var dst [][]float64
+	for _, tc := range []struct {
+		c  Codec
+		cs [][]float64
+	}{
+		{
+			c:  defaultCodec,
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			c:  defaultCodec,
+			cs: [][]float64{{1, 2}},
+		},
+		{
+			c:  Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{1e5, 1e5, 1e2}},
+			cs: [][]float64{{38.5, -120.2, 100.25}, {40.7, -120.95, 99.5}},
+		},
+		{
+			c:  defaultCodec,
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}, {1, 2}},
+		},
+	} {
+		var err error
+		var rest []byte
+		dst, rest, err = tc.c.DecodeCoordsInto(dst, tc.c.EncodeCoords(nil, tc.cs))
+		assert.NoError(t, err)
+		assert.Empty(t, rest)
+		assert.Equal(t, tc.cs, dst)
+	}
+	for _, s := range []string{"", "_p~iF", "_p~iF~ps|U>"} {
+		_, _, err := DecodeCoords([]byte(s))
+		got, _, gotErr := defaultCodec.DecodeCoordsInto(dst, []byte(s))
+		assert.Equal(t, err, gotErr)
+		assert.NotNil(t, got)
+		assert.Empty(t, got)
+		assert.Equal(t, cap(dst), cap(got))
+	}
+}
+
+func TestDecodeCoordsIntoAllocs(t *testing.T) {
+	buf := EncodeCoords(benchmarkCoords(100))
+	dst, _, err := defaultCodec.DecodeCoordsInto(nil, buf)
+	assert.NoError(t, err)
+	allocs := testing.AllocsPerRun(10, func() {
+		if dst, _, err = defaultCodec.DecodeCoordsInto(dst, buf); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.True(t, allocs <= 1)
+}
+
+func TestDecodeCoordsN(t *testing.T) {
+	for _, tc := range []struct {
+		c    Codec
+		s    string
+		n    int
+		cs   [][]float64
+		rest string
+	}{
+		{
+			c:    defaultCodec,
+			s:    "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			n:    3,
+			cs:   [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			rest: "",
+		},
+		{
+			c:    defaultCodec,
+			s:    "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			n:    2,
+			cs:   [][]float64{{38.5, -120.2}, {40.7, -120.95}},
+			rest: "_mqNvxq`@",
+		},
+		{
+			c:    defaultCodec,
+			s:    "_p~iF~ps|U\x00\x01",
+			n:    1,
+			cs:   [][]float64{{38.5, -120.2}},
+			rest: "\x00\x01",
+		},
+		{
+			c:    defaultCodec,
+			s:    "abc",
+			n:    0,
+			cs:   [][]float64{},
+			rest: "abc",
+		},
+		{
+			c:    Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{1e5, 1e5, 1e2}},
+			s:    "_p~iF~ps|UqqR_ulLnnqCtC",
+			n:    2,
+			cs:   [][]float64{{38.5, -120.2, 100.25}, {40.7, -120.95, 99.5}},
+			rest: "",
+		},
+	} {
+		got, rest, err := tc.c.DecodeCoordsN([]byte(tc.s), tc.n)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.cs, got)
+		assert.Equal(t, tc.rest, string(rest))
+	}
+	got, rest, err := DecodeCoordsN([]byte("_p~iF~ps|U_ulLnnqC"), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{38.5, -120.2}}, got)
+	assert.Equal(t, "_ulLnnqC", string(rest))
+}
+
+func TestDecodeCoordsNErrors(t *testing.T) {
+	for _, tc := range []struct {
+		s   string
+		n   int
+		err error
+	}{
+		{s: "_p~iF~ps|U", n: -1, err: errCountMismatch},
+		{s: "_p~iF~ps|U", n: 2, err: errUnterminatedSequence},
+		{s: "_p~iF~ps|U_ulLnnq", n: 2, err: errUnterminatedSequence},
+		{s: "_p~iF~ps|U_ulL>>>>", n: 2, err: errInvalidByte},
+		{s: "", n: 1, err: errUnterminatedSequence},
+	} {
+		_, _, err := DecodeCoordsN([]byte(tc.s), tc.n)
+		assert.Equal(t, tc.err, err)
+	}
+}
+
+func TestDecodeCoordsHint(t *testing.T) {
+	for _, sizeHint := range []int{-1, 0, 1, 3, 100} {
+		got, b, err := defaultCodec.DecodeCoordsHint([]byte("_p~iF~ps|U_ulLnnqC_mqNvxq`@"), sizeHint)
+		assert.NoError(t, err)
+		assert.Empty(t, b)
+		assert.Equal(t, [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}, got)
+	}
+}
+
+func TestDecodeCoordsArena(t *testing.T) {
+	coords, flatCoords, err := defaultCodec.DecodeCoordsArena([]byte("_p~iF~ps|U_ulLnnqC_mqNvxq`@"))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}, coords)
+	assert.Equal(t, []float64{38.5, -120.2, 40.7, -120.95, 43.252, -126.453}, flatCoords)
+	coords[1][0] = 0
+	assert.Equal(t, 0.0, flatCoords[2])
+	assert.Equal(t, 2, cap(coords[0]))
+
+	for _, tc := range []struct {
+		s   string
+		err error
+	}{
+		{s: "", err: errUnterminatedSequence},
+		{s: "_p~iF~ps|U_p~iF", err: errUnterminatedSequence},
+		{s: "_p~iF~ps|U_p~iF~ps|", err: errUnterminatedSequence},
+		{s: "_p~iF~ps|U_p~iF>", err: errInvalidByte},
+	} {
+		_, _, err := defaultCodec.DecodeCoordsArena([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+	}
+}
+
+func TestReusable(t *testing.T) {
+	r := NewReusable(defaultCodec)
+	for _, tc := range []struct {
+		s   string
+		cs  [][]float64
+		err error
+	}{
+		{
+			s:  "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			s:  "_p~iF~ps|U",
+			cs: [][]float64{{38.5, -120.2}},
+		},
+		{
+			s:   "",
+			err: errUnterminatedSequence,
+		},
+		{
+			s:   "_p~iF~ps|U_ulL",
+			err: errUnterminatedSequence,
+		},
+		{
+			s:   "_p~iF~ps|U>",
+			err: errInvalidByte,
+		},
+		{
+			s:  "_p~iF~ps|U_ulLnnqC_mqNvxq`@_p~iF~ps|U",
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}, {81.752, -246.653}},
+		},
+	} {
+		got, err := r.Decode([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+		assert.Equal(t, tc.cs, got)
+	}
+}
+
+func benchmarkCoords(n int) [][]float64 {
+	r := rand.New(rand.NewSource(1))
+	coords := make([][]float64, n)
+	lat, lng := 45.0, 5.0
+	for i := range coords {
+		lat += 0.001 * (r.Float64() - 0.5)
+		lng += 0.001 * (r.Float64() - 0.5)
+		coords[i] = []float64{lat, lng}
+	}
+	return coords
+}
+
+func BenchmarkDecodeCoords(b *testing.B) {
+	buf := EncodeCoords(benchmarkCoords(50000))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := DecodeCoords(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDefaultCodecSpecialization(t *testing.T) {
+	f := func(qc QuickCoords) bool {
+		buf := defaultCodec.encodeCoords(nil, make([]int, 2), [][]float64(qc))
+		if string(buf) != string(encodeCoordsDefault(nil, [][]float64(qc))) {
+			return false
+		}
+		want, _, err := defaultCodec.decodeCoordsHint(buf, 1)
+		if err != nil {
+			return false
+		}
+		got, _, err := decodeCoordsDefault(buf)
+		return err == nil && reflect.DeepEqual(want, got)
+	}
+	assert.NoError(t, quick.Check(f, nil))
+	for _, s := range []string{"", "_p~iF", "_p~iF~ps|U_p~iF", "_p~iF~ps|U>", "_p~iF~ps|U_p~iF>"} {
+		_, _, wantErr := defaultCodec.decodeCoordsHint([]byte(s), 1)
+		_, _, gotErr := decodeCoordsDefault([]byte(s))
+		assert.Equal(t, wantErr, gotErr)
+	}
+}
+
+func BenchmarkDecodeCoordsGeneric(b *testing.B) {
+	buf := EncodeCoords(benchmarkCoords(50000))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := defaultCodec.decodeCoordsHint(buf, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeCoords(b *testing.B) {
+	coords := benchmarkCoords(50000)
+	buf := make([]byte, 0, len(EncodeCoords(coords)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = defaultCodec.EncodeCoords(buf, coords)
+	}
+}
+
+func BenchmarkEncodeCoordsGeneric(b *testing.B) {
+	coords := benchmarkCoords(50000)
+	buf := make([]byte, 0, len(EncodeCoords(coords)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = defaultCodec.encodeCoords(buf, make([]int, 2), coords)
+	}
+}
+
+func BenchmarkDecodeCoordsHint(b *testing.B) {
+	// The default codec ignores the size hint, so use a three-dimensional one.
+	c := Codec{Dim: 3, Scale: 1e5}
+	coords := benchmarkCoords(50000)
+	for i, coord := range coords {
+		coords[i] = append(coord, float64(i%1000))
+	}
+	buf := c.EncodeCoords(nil, coords)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := c.DecodeCoordsHint(buf, 50000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeCoordsInto(b *testing.B) {
+	buf := EncodeCoords(benchmarkCoords(50000))
+	var dst [][]float64
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if dst, _, err = defaultCodec.DecodeCoordsInto(dst, buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeCoordsArena(b *testing.B) {
+	buf := EncodeCoords(benchmarkCoords(50000))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := defaultCodec.DecodeCoordsArena(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestAppendRaw(t *testing.T) {
+	for _, tc := range []struct {
+		priorLast []int
+		segment   [][]float64
+		last      []int
+	}{
+		{
+			priorLast: []int{0, 0},
+			segment:   [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			last:      []int{4325200, -12645300},
+		},
+		{
+			priorLast: []int{3850000, -12020000},
+			segment:   [][]float64{{40.7, -120.95}, {43.252, -126.453}},
+			last:      []int{4325200, -12645300},
+		},
+		{
+			priorLast: []int{3850000, -12020000},
+			segment:   [][]float64{{40.7, -120.95}},
+			last:      []int{4070000, -12095000},
+		},
+		{
+			priorLast: []int{3850000, -12020000},
+			segment:   nil,
+			last:      []int{3850000, -12020000},
+		},
+	} {
+		prefix := []byte("prefix")
+		got, gotLast, err := defaultCodec.AppendRaw(prefix, EncodeCoords(tc.segment), tc.priorLast)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.last, gotLast)
+		want := append([]byte("prefix"), defaultCodec.encodeCoords(nil, append([]int(nil), tc.priorLast...), tc.segment)...)
+		assert.Equal(t, string(want), string(got))
+	}
+}
+
+func TestAppendRawErrors(t *testing.T) {
+	for _, tc := range []struct {
+		priorLast []int
+		s         string
+		err       error
+	}{
+		{priorLast: []int{0}, s: "??", err: errDimensionalMismatch},
+		{priorLast: []int{0, 0}, s: "?", err: errUnterminatedSequence},
+		{priorLast: []int{0, 0}, s: "???", err: errUnterminatedSequence},
+		{priorLast: []int{0, 0}, s: "??>?", err: errInvalidByte},
+	} {
+		_, _, err := defaultCodec.AppendRaw(nil, []byte(tc.s), tc.priorLast)
+		assert.Equal(t, tc.err, err)
+	}
+}
+
+func TestDecodeCoordsPartial(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		cs       [][]float64
+		consumed int
+		err      error
+	}{
+		{
+			s:        "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			cs:       [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			consumed: 27,
+		},
+		{
+			s:        "_p~iF~ps|U_ulLnnqC_mqN>",
+			cs:       [][]float64{{38.5, -120.2}, {40.7, -120.95}},
+			consumed: 18,
+			err:      errInvalidByte,
+		},
+		{
+			s:        "_p~iF~ps|U_ulLnnqC_mqNvxq",
+			cs:       [][]float64{{38.5, -120.2}, {40.7, -120.95}},
+			consumed: 18,
+			err:      errUnterminatedSequence,
+		},
+		{
+			s:   "_p~iF",
+			err: errUnterminatedSequence,
+		},
+	} {
+		got, consumed, err := defaultCodec.DecodeCoordsPartial([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+		assert.Equal(t, tc.cs, got)
+		assert.Equal(t, tc.consumed, consumed)
+	}
+}
+
+func BenchmarkReusable(b *testing.B) {
+	buf := EncodeCoords(benchmarkCoords(50000))
+	r := NewReusable(defaultCodec)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.Decode(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDecodeCoordsExpect(t *testing.T) {
+	for _, tc := range []struct {
+		c        Codec
+		s        string
+		expected int
+		cs       [][]float64
+		err      error
+	}{
+		{
+			c:        defaultCodec,
+			s:        "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			expected: 3,
+			cs:       [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			c:        defaultCodec,
+			s:        "_p~iF~ps|U_ulLnnqC",
+			expected: 3,
+			err:      errCountMismatch,
+		},
+		{
+			c:        defaultCodec,
+			s:        "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			expected: 2,
+			err:      errCountMismatch,
+		},
+		{
+			c:        defaultCodec,
+			s:        "_p~iF~ps|U_ulL",
+			expected: 2,
+			err:      errUnterminatedSequence,
+		},
+		{
+			c:        defaultCodec,
+			s:        "_p~iF~ps|U",
+			expected: -1,
+			err:      errCountMismatch,
+		},
+		{
+			c:        Codec{Dim: 3, Scale: 1},
+			s:        "???",
+			expected: 1 << (strconv.IntSize - 2),
+			err:      errCountMismatch,
+		},
+	} {
+		got, err := tc.c.DecodeCoordsExpect([]byte(tc.s), tc.expected)
+		assert.Equal(t, tc.err, err)
+		assert.Equal(t, tc.cs, got)
+	}
+}
+
+func TestRadians(t *testing.T) {
+	c := Codec{Dim: 2, Scale: 1e5, Radians: true}
+	cs := [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}
+	rcs := make([][]float64, len(cs))
+	for i, coord := range cs {
+		rcs[i] = []float64{coord[0] * math.Pi / 180, coord[1] * math.Pi / 180}
+	}
+	s := "_p~iF~ps|U_ulLnnqC_mqNvxq`@"
+	assert.Equal(t, s, string(c.EncodeCoords(nil, rcs)))
+	got, _, err := c.DecodeCoords([]byte(s))
+	assert.NoError(t, err)
+	assert.True(t, CoordsApproxEqual(rcs, got, 1e-12))
+	gotFlat, _, err := c.DecodeFlatCoords(nil, []byte(s))
+	assert.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{rcs[0][0], rcs[0][1], rcs[1][0], rcs[1][1], rcs[2][0], rcs[2][1]}, gotFlat, 1e-12)
+}
+
+func TestRadiansQuick(t *testing.T) {
+	c := Codec{Dim: 2, Scale: 1e5, Radians: true}
+	f := func(qc QuickCoords) bool {
+		rcs := make([][]float64, len(qc))
+		for i, coord := range qc {
+			rcs[i] = []float64{coord[0] * math.Pi / 180, coord[1] * math.Pi / 180}
+		}
+		got, _, err := c.DecodeCoords(c.EncodeCoords(nil, rcs))
+		return err == nil && CoordsApproxEqual(rcs, got, 5e-6*math.Pi/180+1e-12)
+	}
+	assert.NoError(t, quick.Check(f, nil))
+}
+
+func TestDecodeFlatInts(t *testing.T) {
+	for _, tc := range []struct {
+		c   Codec
+		s   string
+		is  []int
+		err error
+	}{
+		{
+			c:  Codec{Dim: 2, Scale: 1e5},
+			s:  "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			is: []int{3850000, -12020000, 4070000, -12095000, 4325200, -12645300},
+		},
+		{
+			c:  Codec{Dim: 3, Scale: 1e5},
+			s:  "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			is: []int{3850000, -12020000, 220000, 3775000, -11764800, -330300},
+		},
+		{
+			c:  Codec{Dim: 2, Scale: 1e5},
+			s:  "",
+			is: []int{},
+		},
+		{
+			c:   Codec{Dim: 2, Scale: 1e5},
+			s:   "_p~iF~ps|U_ulL",
+			err: errUnterminatedSequence,
+		},
+		{
+			c:   Codec{Dim: 2, Scale: 1e5},
+			s:   "_p~iF~ps|U_ulL>",
+			err: errInvalidByte,
+		},
+	} {
+		got, err := tc.c.DecodeFlatInts([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+		assert.Equal(t, tc.is, got)
+	}
+}
+
+func TestEncodeCoordsChecked(t *testing.T) {
+	for _, tc := range []struct {
+		c   Codec
+		cs  [][]float64
+		s   string
+		err error
+	}{
+		{
+			c:  Codec{Dim: 2, Scale: 1e5},
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			s:  "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			c:  Codec{Dim: 2, Scale: 1e6},
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			s:  "_izlhA~rlgdF_{geC~ywl@_kwzCn`{nI",
+		},
+		{
+			c:  Codec{Dim: 2, Scale: 1, ChunkBits: 6},
+			cs: [][]float64{{0, 0}, {31, -32}},
+			s:  "??}~",
+		},
+		{
+			c:   Codec{Dim: 2, Scale: 1e5, ChunkBits: 6},
+			cs:  [][]float64{{38.5, -120.2}},
+			err: errNonASCII,
+		},
+		{
+			c:   Codec{Dim: 1, Scale: 1, ChunkBits: 6},
+			cs:  [][]float64{{32}},
+			err: errNonASCII,
+		},
+	} {
+		got, err := tc.c.EncodeCoordsChecked([]byte("\x80"), tc.cs)
+		assert.Equal(t, tc.err, err)
+		if tc.err != nil {
+			assert.Nil(t, got)
+			continue
+		}
+		assert.Equal(t, "\x80"+tc.s, string(got))
+	}
+}
+
+func TestCoordsPermuted(t *testing.T) {
+	for _, tc := range []struct {
+		c    Codec
+		cs   [][]float64
+		perm []int
+		want [][]float64
+	}{
+		{
+			c:    Codec{Dim: 2, Scale: 1e5},
+			cs:   [][]float64{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}},
+			perm: []int{1, 0},
+			want: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			c:    Codec{Dim: 3, Scale: 1e5},
+			cs:   [][]float64{{-120.2, 38.5, 100}, {-120.95, 40.7, 200}},
+			perm: []int{1, 0, 2},
+			want: [][]float64{{38.5, -120.2, 100}, {40.7, -120.95, 200}},
+		},
+		{
+			c:    Codec{Dim: 3, Scale: 1e5},
+			cs:   [][]float64{{1, 2, 3}, {4, 5, 6}},
+			perm: []int{2, 0, 1},
+			want: [][]float64{{3, 1, 2}, {6, 4, 5}},
+		},
+	} {
+		buf, err := tc.c.EncodeCoordsPermuted(nil, tc.cs, tc.perm)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.c.EncodeCoords(nil, tc.want), buf)
+		got, err := tc.c.DecodeCoordsPermuted(buf, tc.perm)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.cs, got)
+	}
+}
+
+func TestCoordsPermutedErrors(t *testing.T) {
+	for _, tc := range []struct {
+		perm []int
+		cs   [][]float64
+		err  error
+	}{
+		{perm: []int{0}, err: errInvalidPermutation},
+		{perm: []int{0, 0}, err: errInvalidPermutation},
+		{perm: []int{0, 2}, err: errInvalidPermutation},
+		{perm: []int{-1, 0}, err: errInvalidPermutation},
+		{perm: []int{0, 1}, cs: [][]float64{{1}}, err: errDimensionalMismatch},
+	} {
+		_, err := defaultCodec.EncodeCoordsPermuted(nil, tc.cs, tc.perm)
+		assert.Equal(t, tc.err, err)
+	}
+	_, err := defaultCodec.DecodeCoordsPermuted([]byte("??"), []int{1, 1})
+	assert.Equal(t, errInvalidPermutation, err)
+	_, err = defaultCodec.DecodeCoordsPermuted([]byte("?"), []int{1, 0})
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeCoordsMaxDelta(t *testing.T) {
+	for _, tc := range []struct {
+		cs       [][]float64
+		maxDelta float64
+	}{
+		{cs: [][]float64{{38.5, -120.2}}, maxDelta: 0},
+		{cs: [][]float64{{38.5, -120.2}, {38.5, -120.2}}, maxDelta: 0},
+		{cs: [][]float64{{0, 0}, {0.00003, 0.00004}, {0.00004, 0.00004}}, maxDelta: 5},
+		{cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}, maxDelta: math.Hypot(255200, 550300)},
+	} {
+		got, maxDelta, err := defaultCodec.DecodeCoordsMaxDelta(EncodeCoords(tc.cs))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.cs, got)
+		assert.InDelta(t, tc.maxDelta, maxDelta, 1e-9)
+	}
+	_, _, err := defaultCodec.DecodeCoordsMaxDelta([]byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestPerDimension(t *testing.T) {
+	for _, tc := range []struct {
+		c        Codec
+		channels [][]float64
+	}{
+		{
+			c:        Codec{Dim: 2, Scale: 1e5},
+			channels: [][]float64{{38.5, 40.7, 43.252}, {-120.2, -120.95, -126.453}},
+		},
+		{
+			c:        Codec{Dim: 3, Scale: 1e2},
+			channels: [][]float64{{0, 1, 2, 3}, {10.5, 10.25, 10.75, 11}, {-1, -2, -3, -4}},
+		},
+		{
+			c:        Codec{Dim: 1, Scale: 1},
+			channels: [][]float64{{1, 2, 4, 8}},
+		},
+		{
+			c:        Codec{Dim: 2, Scale: 1e5},
+			channels: [][]float64{{}, {}},
+		},
+	} {
+		buf, err := tc.c.EncodePerDimension(nil, tc.channels)
+		assert.NoError(t, err)
+		got, err := tc.c.DecodePerDimension(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.channels, got)
+	}
+	_, err := defaultCodec.EncodePerDimension(nil, [][]float64{{1, 2}, {3}})
+	assert.Equal(t, errLengthMismatch, err)
+	_, err = defaultCodec.DecodePerDimension([]byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeWindow(t *testing.T) {
+	cs := [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}, {44, -127}}
+	for _, tc := range []struct {
+		n    int
+		want [][][]float64
+	}{
+		{
+			n:    1,
+			want: [][][]float64{cs[0:1], cs[1:2], cs[2:3], cs[3:4]},
+		},
+		{
+			n:    2,
+			want: [][][]float64{cs[0:1], cs[0:2], cs[1:3], cs[2:4]},
+		},
+		{
+			n:    5,
+			want: [][][]float64{cs[0:1], cs[0:2], cs[0:3], cs[0:4]},
+		},
+	} {
+		var got [][][]float64
+		assert.NoError(t, defaultCodec.DecodeWindow(EncodeCoords(cs), tc.n, func(window [][]float64) error {
+			copied := make([][]float64, len(window))
+			for i, coord := range window {
+				copied[i] = append([]float64(nil), coord...)
+			}
+			got = append(got, copied)
+			return nil
+		}))
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestDecodeWindowErrors(t *testing.T) {
+	nop := func([][]float64) error { return nil }
+	assert.Equal(t, errInvalidWindow, defaultCodec.DecodeWindow([]byte("_p~iF~ps|U"), 0, nop))
+	assert.Equal(t, errUnterminatedSequence, defaultCodec.DecodeWindow([]byte("_p~iF~ps|U_ulL"), 2, nop))
+	calls := 0
+	errStop := errors.New("stop")
+	assert.Equal(t, errStop, defaultCodec.DecodeWindow([]byte("_p~iF~ps|U_ulLnnqC_mqNvxq`@"), 2, func([][]float64) error {
+		calls++
+		return errStop
+	}))
+	assert.Equal(t, 1, calls)
+}
+
+func TestCoordsUnwrapped(t *testing.T) {
+	for _, tc := range []struct {
+		cs   [][]float64
+		s    string
+		want [][]float64
+	}{
+		{
+			cs:   [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			s:    "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			want: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			cs:   [][]float64{{10, 179.5}, {10.5, -179.5}, {11, -178}, {11, 179}},
+			s:    "_c`|@_rqfa@_t`B_ibE_t`B_~cH?~|hQ",
+			want: [][]float64{{10, 179.5}, {10.5, -179.5}, {11, -178}, {11, 179}},
+		},
+		{
+			cs:   [][]float64{{0, 180}, {0, -179}},
+			s:    "?_gsia@?_ibE",
+			want: [][]float64{{0, -180}, {0, -179}},
+		},
+	} {
+		buf := defaultCodec.EncodeCoordsUnwrapped(nil, tc.cs)
+		assert.Equal(t, tc.s, string(buf))
+		got, _, err := defaultCodec.DecodeCoordsWrapped(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+	c := Codec{Dim: 2, Scale: 1e5, Radians: true}
+	cs := [][]float64{{radians(10), radians(179.5)}, {radians(10.5), radians(-179.5)}}
+	buf := c.EncodeCoordsUnwrapped(nil, cs)
+	assert.Equal(t, "_c`|@_rqfa@_t`B_ibE", string(buf))
+	got, _, err := c.DecodeCoordsWrapped(buf)
+	assert.NoError(t, err)
+	for i := range cs {
+		assert.InDeltaSlice(t, cs[i], got[i], 1e-12)
+	}
+	_, _, err = defaultCodec.DecodeCoordsWrapped([]byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestCoordsFramed(t *testing.T) {
+	for _, tc := range []struct {
+		c  Codec
+		cs [][]float64
+		s  string
+	}{
+		{
+			c:  defaultCodec,
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			s:  "B_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			c:  defaultCodec,
+			cs: [][]float64{},
+			s:  "?",
+		},
+		{
+			c:  Codec{Dim: 1, Scale: 1},
+			cs: [][]float64{{1}, {2}},
+			s:  "AAA",
+		},
+	} {
+		buf := tc.c.EncodeCoordsFramed(nil, tc.cs)
+		assert.Equal(t, tc.s, string(buf))
+		buf = tc.c.EncodeCoordsFramed(buf, tc.cs)
+		for i := 0; i < 2; i++ {
+			var got [][]float64
+			var err error
+			got, buf, err = tc.c.DecodeCoordsFramed(buf)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.cs, got)
+		}
+		assert.Empty(t, buf)
+	}
+}
+
+func TestDecodeCoordsFramedErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"_",
+		"B_p~iF~ps|U",
+		"A_p~iF~ps|U_ulL",
+		"~~~~~~~~~~~~?",
+	} {
+		_, _, err := defaultCodec.DecodeCoordsFramed([]byte(s))
+		assert.Error(t, err, s)
+	}
+}
+
+func TestCoordsDict(t *testing.T) {
+	base := [][]float64{{38.5, -120.2}, {40.7, -120.95}}
+	for _, tc := range []struct {
+		cs [][]float64
+		s  string
+	}{
+		{
+			cs: base,
+			s:  "@????",
+		},
+		{
+			cs: [][]float64{{38.50001, -120.2}, {40.7, -120.95002}},
+			s:  "@A??B",
+		},
+		{
+			cs: [][]float64{{38.5, -120.2}},
+			s:  "?_p~iF~ps|U",
+		},
+		{
+			cs: nil,
+			s:  "?",
+		},
+	} {
+		buf := defaultCodec.EncodeCoordsDict(base, tc.cs)
+		assert.Equal(t, tc.s, string(buf))
+		got, err := defaultCodec.DecodeCoordsDict(base, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.cs, got)
+	}
+}
+
+func TestDecodeCoordsDictErrors(t *testing.T) {
+	base := [][]float64{{38.5, -120.2}, {40.7, -120.95}}
+	for _, tc := range []struct {
+		s   string
+		err error
+	}{
+		{s: "", err: errUnterminatedSequence},
+		{s: "A", err: errInvalidTag},
+		{s: "@??", err: errCountMismatch},
+		{s: "@??????", err: errCountMismatch},
+		{s: "@???_", err: errUnterminatedSequence},
+	} {
+		_, err := defaultCodec.DecodeCoordsDict(base, []byte(tc.s))
+		assert.Equal(t, tc.err, err)
+	}
+}
+
+func TestPutInt(t *testing.T) {
+	for _, i := range []int{0, 1, -1, 17, -17, 1 << 20, -1 << 20, math.MaxInt32, math.MinInt32, 1<<(strconv.IntSize-1) - 1, -1 << (strconv.IntSize - 1)} {
+		var dst [MaxEncodedLen]byte
+		n := PutInt(&dst, i)
+		assert.Equal(t, string(EncodeInt64(nil, int64(i))), string(dst[:n]))
+		got, rest, err := DecodeInt(dst[:n])
+		assert.NoError(t, err)
+		assert.Equal(t, i, got)
+		assert.Empty(t, rest)
+	}
+}
+
+func TestPutUint(t *testing.T) {
+	for _, u := range []uint{0, 1, 31, 32, 1 << 20, math.MaxUint32, 1<<strconv.IntSize - 1} {
+		var dst [MaxEncodedLen]byte
+		n := PutUint(&dst, u)
+		assert.Equal(t, string(EncodeUint64(nil, uint64(u))), string(dst[:n]))
+		got, rest, err := DecodeUint(dst[:n])
+		assert.NoError(t, err)
+		assert.Equal(t, u, got)
+		assert.Empty(t, rest)
+	}
+}
+
+func TestCoordsWithCRC(t *testing.T) {
+	cs := [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}
+	buf := defaultCodec.EncodeCoordsWithCRC(cs)
+	assert.Equal(t, "_p~iF~ps|U_ulLnnqC_mqNvxq`@l{jqxmB", string(buf))
+	got, err := defaultCodec.DecodeCoordsVerified(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, cs, got)
+}
+
+func TestDecodeCoordsVerifiedErrors(t *testing.T) {
+	for _, tc := range []struct {
+		s   string
+		err error
+	}{
+		{s: "", err: errUnterminatedSequence},
+		{s: "_p~iF~ps|U_ulLnnqC_mqNvxq`@l{jqxm", err: errUnterminatedSequence},
+		{s: "_p~iF~ps|U_ulLnnqC_mqNvxq`@l{jqxmC", err: errChecksumMismatch},
+		{s: "_p~iF~ps|U_ulLnnqC_mqNvxq`Al{jqxmB", err: errChecksumMismatch},
+		{s: "_p~iF~ps|U_ulLnnqC_mqNvxq`@", err: errChecksumMismatch},
+		{s: "l{jqxmB", err: errChecksumMismatch},
+	} {
+		_, err := defaultCodec.DecodeCoordsVerified([]byte(tc.s))
+		assert.Equal(t, tc.err, err, tc.s)
+	}
+}
+
+func TestDecodeCoordsTrim(t *testing.T) {
+	cs := [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}
+	for _, s := range []string{
+		"_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		"_p~iF~ps|U_ulLnnqC_mqNvxq`@\n",
+		"_p~iF~ps|U_ulLnnqC_mqNvxq`@\r\n",
+		" \t_p~iF~ps|U_ulLnnqC_mqNvxq`@ \t\n",
+	} {
+		got, err := DecodeCoordsTrim([]byte(s))
+		assert.NoError(t, err)
+		assert.Equal(t, cs, got)
+	}
+	for _, tc := range []struct {
+		s   string
+		err error
+	}{
+		{s: "_p~iF~ps|U _ulLnnqC_mqNvxq`@", err: errInvalidByte},
+		{s: "_p~iF~ps|U_ulLnnqC_mqNvxq`@\v", err: errInvalidByte},
+	} {
+		_, err := DecodeCoordsTrim([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+	}
+	_, _, err := DecodeCoords([]byte("_p~iF~ps|U_ulLnnqC_mqNvxq`@\n"))
+	assert.Equal(t, errInvalidByte, err)
+}
+
+func TestRotateRing(t *testing.T) {
+	square := [][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}
+	for _, tc := range []struct {
+		coords     [][]float64
+		startIndex int
+		want       [][]float64
+	}{
+		{
+			coords:     square,
+			startIndex: 0,
+			want:       square,
+		},
+		{
+			coords:     square,
+			startIndex: 2,
+			want:       [][]float64{{1, 1}, {1, 0}, {0, 0}, {0, 1}, {1, 1}},
+		},
+		{
+			coords:     square,
+			startIndex: 3,
+			want:       [][]float64{{1, 0}, {0, 0}, {0, 1}, {1, 1}, {1, 0}},
+		},
+		{
+			coords:     [][]float64{{0, 0}, {0, 1}, {1, 1}},
+			startIndex: 1,
+			want:       [][]float64{{0, 1}, {1, 1}, {0, 0}},
+		},
+	} {
+		got, err := defaultCodec.RotateRing(EncodeCoords(tc.coords), tc.startIndex)
+		assert.NoError(t, err)
+		assert.Equal(t, string(EncodeCoords(tc.want)), string(got))
+	}
+}
+
+func TestRotateRingErrors(t *testing.T) {
+	square := EncodeCoords([][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}})
+	for _, tc := range []struct {
+		buf        []byte
+		startIndex int
+		err        error
+	}{
+		{buf: square, startIndex: -1, err: errIndexOutOfRange},
+		{buf: square, startIndex: 4, err: errIndexOutOfRange},
+		{buf: nil, startIndex: 0, err: errIndexOutOfRange},
+		{buf: []byte("_p~iF"), startIndex: 0, err: errUnterminatedSequence},
+	} {
+		_, err := defaultCodec.RotateRing(tc.buf, tc.startIndex)
+		assert.Equal(t, tc.err, err)
+	}
+}
+
+func TestDecodeCoordsWithAltCheck(t *testing.T) {
+	c := Codec{Dim: 3, Scale: 1e5}
+	for _, tc := range []struct {
+		coords  [][]float64
+		flagged []int
+	}{
+		{
+			coords: [][]float64{{38.5, -120.2, 100}},
+		},
+		{
+			coords: [][]float64{{38.5, -120.2, 100}, {38.6, -120.3, 150}, {38.7, -120.4, 110}},
+		},
+		{
+			coords:  [][]float64{{38.5, -120.2, 100}, {38.6, -120.3, 900}, {38.7, -120.4, 110}, {38.8, -120.5, 160}},
+			flagged: []int{1, 2},
+		},
+		{
+			coords:  [][]float64{{38.5, -120.2, 100}, {38.6, -120.3, 100}, {38.7, -120.4, 49}},
+			flagged: []int{2},
+		},
+	} {
+		got, flagged, err := c.DecodeCoordsWithAltCheck(c.EncodeCoords(nil, tc.coords), 50)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.coords, got)
+		assert.Equal(t, tc.flagged, flagged)
+	}
+	_, _, err := defaultCodec.DecodeCoordsWithAltCheck([]byte("_p~iF~ps|U"), 50)
+	assert.Equal(t, errDimensionalMismatch, err)
+	_, _, err = c.DecodeCoordsWithAltCheck([]byte("_p~iF~ps|U"), 50)
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeCoordsResume(t *testing.T) {
+	cs := [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}
+	buf := EncodeCoords(cs)
+	for _, max := range []int{0, 1, 2, 3, 4} {
+		var got [][]float64
+		var cur Cursor
+		for {
+			coords, next, err := defaultCodec.DecodeCoordsResume(buf, cur, max)
+			assert.NoError(t, err)
+			if len(coords) == 0 {
+				assert.Equal(t, cur, next)
+				break
+			}
+			if max > 0 {
+				assert.True(t, len(coords) <= max)
+			}
+			got = append(got, coords...)
+			cur = next
+		}
+		assert.Equal(t, cs, got)
+		assert.Equal(t, Cursor{Last: []int{4325200, -12645300}, Offset: len(buf)}, cur)
+	}
+	coords, cur, err := defaultCodec.DecodeCoordsResume(nil, Cursor{}, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, coords)
+	assert.Equal(t, Cursor{}, cur)
+}
+
+func TestDecodeCoordsResumeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		s   string
+		cur Cursor
+		err error
+	}{
+		{s: "_p~iF~ps|U", cur: Cursor{Last: []int{0}}, err: errDimensionalMismatch},
+		{s: "_p~iF~ps|U", cur: Cursor{Offset: -1}, err: errIndexOutOfRange},
+		{s: "_p~iF~ps|U", cur: Cursor{Offset: 11}, err: errIndexOutOfRange},
+		{s: "_p~iF~ps|U_ulL", cur: Cursor{Last: []int{3850000, -12020000}, Offset: 10}, err: errUnterminatedSequence},
+	} {
+		_, cur, err := defaultCodec.DecodeCoordsResume([]byte(tc.s), tc.cur, 0)
+		assert.Equal(t, tc.err, err)
+		assert.Equal(t, tc.cur, cur)
+	}
+}
+
+func TestDecodeRing(t *testing.T) {
+	cs := [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}, {44, -127}}
+	for _, tc := range []struct {
+		capacity int
+		evicted  [][]float64
+		want     [][]float64
+	}{
+		{capacity: 1, evicted: cs[:3], want: cs[3:]},
+		{capacity: 3, evicted: cs[:1], want: cs[1:]},
+		{capacity: 4, want: cs},
+		{capacity: 5, want: cs},
+	} {
+		var evicted [][]float64
+		got, err := defaultCodec.DecodeRing(EncodeCoords(cs), tc.capacity, func(coord []float64) {
+			evicted = append(evicted, append([]float64(nil), coord...))
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, tc.evicted, evicted)
+		assert.Equal(t, tc.want, got)
+	}
+	got, err := defaultCodec.DecodeRing(EncodeCoords(cs), 2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, cs[2:], got)
+}
+
+func TestDecodeRingErrors(t *testing.T) {
+	_, err := defaultCodec.DecodeRing([]byte("_p~iF~ps|U"), 0, nil)
+	assert.Equal(t, errInvalidCapacity, err)
+	_, err = defaultCodec.DecodeRing([]byte("_p~iF~ps|U_ulL"), 1, nil)
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeCoordsWithStats(t *testing.T) {
+	for _, tc := range []struct {
+		c     Codec
+		s     string
+		stats DecodeStats
+	}{
+		{
+			c: defaultCodec,
+			s: "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			stats: DecodeStats{
+				Bytes:             27,
+				ContinuationBytes: 21,
+				AvgDeltaLen:       []float64{13.0 / 3, 14.0 / 3},
+			},
+		},
+		{
+			c: Codec{Dim: 1, Scale: 1},
+			s: "??A",
+			stats: DecodeStats{
+				Bytes:             3,
+				ContinuationBytes: 0,
+				AvgDeltaLen:       []float64{1},
+			},
+		},
+	} {
+		coords, stats, err := tc.c.DecodeCoordsWithStats([]byte(tc.s))
+		assert.NoError(t, err)
+		want, _, _ := tc.c.DecodeCoords([]byte(tc.s))
+		assert.Equal(t, want, coords)
+		assert.Equal(t, tc.stats, stats)
+	}
+	_, _, err := defaultCodec.DecodeCoordsWithStats([]byte("_p~iF"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestDecodeCoordsSafe(t *testing.T) {
+	for _, tc := range []struct {
+		c   Codec
+		s   string
+		err error
+	}{
+		{c: defaultCodec, s: "_p~iF~ps|U_ulLnnqC_mqNvxq`@"},
+		{c: Codec{Dim: 3, Scale: 1e5}, s: "_p~iF~ps|U_ulL"},
+		{c: defaultCodec, s: "_p~iF", err: errUnterminatedSequence},
+		{c: Codec{Dim: 0, Scale: 1e5}, s: "_p~iF", err: errDimensionalMismatch},
+		{c: Codec{Dim: -1, Scale: 1e5}, s: "_p~iF", err: errDimensionalMismatch},
+	} {
+		got, err := tc.c.DecodeCoordsSafe([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+		if tc.err == nil {
+			want, _, _ := tc.c.DecodeCoords([]byte(tc.s))
+			assert.Equal(t, want, got)
+		}
+	}
+	coords, err := recoverCoords(func() ([][]float64, error) {
+		panic("boom")
+	})
+	assert.Nil(t, coords)
+	assert.EqualError(t, err, "polyline: recovered from panic: boom")
+	assert.True(t, errors.Is(err, errPanic))
+}
+
+func TestDecodeAutoPrecision(t *testing.T) {
+	cs := [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}
+	for _, tc := range []struct {
+		buf       []byte
+		precision int
+	}{
+		{buf: EncodeCoords(cs), precision: 5},
+		{buf: defaultCodec.EncodeCoords([]byte("D"), cs), precision: 5},
+		{buf: Codec{Dim: 2, Scale: 1e6}.EncodeCoords([]byte("E"), cs), precision: 6},
+	} {
+		got, precision, err := DecodeAutoPrecision(tc.buf)
+		assert.NoError(t, err)
+		assert.Equal(t, cs, got)
+		assert.Equal(t, tc.precision, precision)
+	}
+
+	got, precision, err := DecodeAutoPrecision(EncodeCoords([][]float64{{-0.00003, 1}, {2, 3}}))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{-0.00003, 1}, {2, 3}}, got)
+	assert.Equal(t, 5, precision)
+
+	_, _, err = DecodeAutoPrecision([]byte("E_p~iF~ps|U_ulLnnqC_mq"))
+	assert.Equal(t, errUnterminatedSequence, err)
+}
+
+func TestChunkBits(t *testing.T) {
+	cs := [][]float64{{38.5, -120.2}, {40.7, -120.95}}
+	for _, tc := range []struct {
+		chunkBits int
+		s         string
+	}{
+		{chunkBits: 0, s: "_p~iF~ps|U_ulLnnqC"},
+		{chunkBits: 1, s: "AAAAABAAABBBBBBABABABB@BBBBBBAAABAABABBABBBABBA@AAAAAABBABBABBABAB@BBBBABBBBAABAABAA@"},
+		{chunkBits: 2, s: "CCECEFFDDDF@FFFCECDFEFED@CCCFEDFEE@FFEFDECDA"},
+		{chunkBits: 3, s: "GKGNNILBNNGHLLJJ@GGJJJL@NLNKKC"},
+		{chunkBits: 4, s: "OQ]VTF^RQ\\]U@O[UZE^]XSA"},
+		{chunkBits: 5, s: "_p~iF~ps|U_ulLnnqC"},
+		{chunkBits: 6, s: "\x9f\xb7\x96\\\xbe\x87\xac\x9a@\x7f\x9a\xaa@\xae\xa6c"},
+	} {
+		c := Codec{Dim: 2, Scale: 1e5, ChunkBits: tc.chunkBits}
+		buf := c.EncodeCoords(nil, cs)
+		assert.Equal(t, tc.s, string(buf))
+		got, _, err := c.DecodeCoords(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, cs, got)
+		n, err := c.countInts(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, n)
+	}
+}
+
+func TestChunkBitsInts(t *testing.T) {
+	for k := 1; k <= 6; k++ {
+		c := Codec{Dim: 1, Scale: 1, ChunkBits: k}
+		for _, i := range []int{0, 1, -1, 63, -64, 1 << 20, math.MaxInt32, math.MinInt32, 1<<(strconv.IntSize-1) - 1, -1 << (strconv.IntSize - 1)} {
+			buf := c.EncodeInt(nil, i)
+			got, rest, err := c.DecodeInt(buf)
+			assert.NoError(t, err)
+			assert.Equal(t, i, got)
+			assert.Empty(t, rest)
+		}
+	}
+}
+
+func TestChunkBitsErrors(t *testing.T) {
+	c := Codec{Dim: 1, Scale: 1, ChunkBits: 2}
+	for _, tc := range []struct {
+		s   string
+		err error
+	}{
+		{s: "C", err: errUnterminatedSequence},
+		{s: "G", err: errInvalidByte},
+		{s: ">", err: errInvalidByte},
+	} {
+		_, _, err := c.DecodeInt([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+		_, err = c.countInts([]byte(tc.s))
+		assert.Equal(t, tc.err, err)
+	}
+	_, err := Codec{Dim: 2, Scale: 1e5, ChunkBits: 6}.EncodeCoordsChecked(nil, [][]float64{{38.5, -120.2}})
+	assert.Equal(t, errNonASCII, err)
+	assert.Panics(t, func() {
+		Codec{Dim: 2, Scale: 1e5, ChunkBits: 7}.EncodeCoords(nil, [][]float64{{0, 0}})
+	})
+	assert.Panics(t, func() {
+		Codec{Dim: 2, Scale: 1e5, ChunkBits: 4}.EncodeCoordsTagged([][]float64{{0, 0}})
+	})
+}
+
+func TestChunkBitsInvalid(t *testing.T) {
+	for _, chunkBits := range []int{-1, 7} {
+		c := Codec{Dim: 2, Scale: 1e5, ChunkBits: chunkBits}
+		buf := []byte("_p~iF~ps|U")
+		_, _, err := c.DecodeUint(buf)
+		assert.Equal(t, errInvalidChunkBits, err)
+		_, _, err = c.DecodeInt(buf)
+		assert.Equal(t, errInvalidChunkBits, err)
+		_, _, err = c.DecodeCoords(buf)
+		assert.Equal(t, errInvalidChunkBits, err)
+		_, _, err = c.DecodeCoordsInto(nil, buf)
+		assert.Equal(t, errInvalidChunkBits, err)
+		_, err = c.DecodeFlatInts(buf)
+		assert.Equal(t, errInvalidChunkBits, err)
+		_, err = c.CountCoords(buf)
+		assert.Equal(t, errInvalidChunkBits, err)
+		_, err = c.MaxCoordBytes(buf)
+		assert.Equal(t, errInvalidChunkBits, err)
+		assert.False(t, c.Valid(buf))
+		_, err = NewDecoder(strings.NewReader(string(buf)), c).DecodeCoord()
+		assert.Equal(t, errInvalidChunkBits, err)
+		assert.Equal(t, errInvalidChunkBits, TranscodeStream(&strings.Builder{}, strings.NewReader(string(buf)), defaultCodec, c))
+		_, err = c.EncodeCoordsChecked(nil, [][]float64{{0, 0}})
+		assert.Equal(t, errInvalidChunkBits, err)
+		_, err = c.EncodeFlatCoords(nil, []float64{0, 0})
+		assert.Equal(t, errInvalidChunkBits, err)
+		assert.False(t, c.IsStandard())
+		assert.Panics(t, func() { c.EncodeCoords(nil, [][]float64{{0, 0}}) })
+		assert.Panics(t, func() { c.EncodeInt(nil, 0) })
+	}
+}
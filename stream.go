@@ -0,0 +1,362 @@
+package polyline
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// defaultFlushThreshold is the default number of bytes that a BufferedEncoder
+// accumulates before writing them.
+const defaultFlushThreshold = 4096
+
+// A BufferedEncoder encodes coordinates to an io.Writer, accumulating the
+// encoded bytes and writing them in chunks.
+type BufferedEncoder struct {
+	w         io.Writer
+	c         Codec
+	threshold int
+	buf       []byte
+	last      []int
+}
+
+// NewBufferedEncoder returns a new BufferedEncoder that writes coordinates
+// encoded with c to w. Encoded bytes are written to w once at least threshold
+// bytes have accumulated. If threshold is less than or equal to zero then a
+// default of 4096 bytes is used.
+func NewBufferedEncoder(w io.Writer, c Codec, threshold int) *BufferedEncoder {
+	if threshold <= 0 {
+		threshold = defaultFlushThreshold
+	}
+	return &BufferedEncoder{
+		w:         w,
+		c:         c,
+		threshold: threshold,
+		buf:       make([]byte, 0, threshold),
+		last:      make([]int, c.Dim),
+	}
+}
+
+// EncodeCoord encodes the next coordinate, writing the accumulated bytes to the
+// underlying io.Writer if the flush threshold is reached. It returns any
+// error.
+func (e *BufferedEncoder) EncodeCoord(coord []float64) error {
+	if err := e.c.validate(); err != nil {
+		return err
+	}
+	if len(coord) != e.c.Dim {
+		return errDimensionalMismatch
+	}
+	e.buf = e.c.encodeCoords(e.buf, e.last, [][]float64{coord})
+	if len(e.buf) >= e.threshold {
+		return e.Flush()
+	}
+	return nil
+}
+
+// Flush writes any accumulated bytes to the underlying io.Writer. If the write
+// fails then the bytes that were not written are kept, so a later call to Flush
+// can retry.
+func (e *BufferedEncoder) Flush() error {
+	if len(e.buf) == 0 {
+		return nil
+	}
+	n, err := e.w.Write(e.buf)
+	if n < len(e.buf) && err == nil {
+		err = io.ErrShortWrite
+	}
+	if n > 0 {
+		e.buf = e.buf[:copy(e.buf, e.buf[n:])]
+	}
+	return err
+}
+
+// Close flushes any accumulated bytes. It does not close the underlying
+// io.Writer.
+func (e *BufferedEncoder) Close() error {
+	return e.Flush()
+}
+
+// An Encoder encodes coordinates to an io.Writer, writing each coordinate as
+// it is encoded.
+type Encoder struct {
+	w    io.Writer
+	c    Codec
+	buf  []byte
+	last []int
+}
+
+// NewEncoder returns a new Encoder that writes coordinates encoded with c to
+// w.
+func NewEncoder(w io.Writer, c Codec) *Encoder {
+	return &Encoder{
+		w:    w,
+		c:    c,
+		last: make([]int, c.Dim),
+	}
+}
+
+// EncodeCoord encodes the next coordinate and writes it to the underlying
+// io.Writer. It returns any error.
+func (e *Encoder) EncodeCoord(coord []float64) error {
+	if err := e.c.validate(); err != nil {
+		return err
+	}
+	if len(coord) != e.c.Dim {
+		return errDimensionalMismatch
+	}
+	e.buf = e.c.encodeCoords(e.buf[:0], e.last, [][]float64{coord})
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+// Flush flushes the underlying io.Writer if it has a Flush method, as
+// *bufio.Writer and *gzip.Writer do. The Encoder itself does not buffer.
+func (e *Encoder) Flush() error {
+	if f, ok := e.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// A SimplifyingEncoder encodes coordinates incrementally, dropping coordinates
+// that do not contribute to the shape of the line. It holds at most two
+// unencoded coordinates, so its memory use, apart from the encoding itself, is
+// independent of the number of coordinates.
+type SimplifyingEncoder struct {
+	c       Codec
+	epsilon float64
+	buf     []byte
+	last    []int
+	kept    []float64
+	pending []float64
+}
+
+// NewSimplifyingEncoder returns a new SimplifyingEncoder that encodes
+// coordinates with c, keeping a coordinate only if it is more than epsilon
+// from the line between the previous kept coordinate and the next coordinate.
+// Distances are planar in the first two dimensions, using the second as x and
+// the first as y.
+func NewSimplifyingEncoder(c Codec, epsilon float64) *SimplifyingEncoder {
+	return &SimplifyingEncoder{
+		c:       c,
+		epsilon: epsilon,
+		last:    make([]int, c.Dim),
+	}
+}
+
+// WriteCoord adds the next coordinate. The first and last coordinates are
+// always kept. It returns any error.
+func (e *SimplifyingEncoder) WriteCoord(coord []float64) error {
+	if err := e.c.validate(); err != nil {
+		return err
+	}
+	if len(coord) != e.c.Dim || e.c.Dim < 2 {
+		return errDimensionalMismatch
+	}
+	coord = append([]float64(nil), coord...)
+	switch {
+	case e.kept == nil:
+		e.keep(coord)
+	case e.pending == nil:
+		e.pending = coord
+	default:
+		if segmentDistance(e.pending, e.kept, coord) > e.epsilon {
+			e.keep(e.pending)
+		}
+		e.pending = coord
+	}
+	return nil
+}
+
+// keep encodes coord and makes it the last kept coordinate.
+func (e *SimplifyingEncoder) keep(coord []float64) {
+	e.buf = e.c.encodeCoords(e.buf, e.last, [][]float64{coord})
+	e.kept = coord
+}
+
+// Close keeps the last coordinate and returns the encoding of all kept
+// coordinates. The SimplifyingEncoder must not be used after Close.
+func (e *SimplifyingEncoder) Close() []byte {
+	if e.pending != nil {
+		e.keep(e.pending)
+		e.pending = nil
+	}
+	return e.buf
+}
+
+// A Decoder decodes coordinates from an io.Reader.
+type Decoder struct {
+	r    io.ByteReader
+	c    Codec
+	last []int
+}
+
+// NewDecoder returns a new Decoder that reads coordinates encoded with c from
+// r. If r does not implement io.ByteReader then the Decoder buffers reads from
+// r and may read more bytes than it decodes.
+func NewDecoder(r io.Reader, c Codec) *Decoder {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{
+		r:    br,
+		c:    c,
+		last: make([]int, c.Dim),
+	}
+}
+
+// DecodeCoord decodes the next coordinate. It returns io.EOF when the
+// underlying io.Reader is exhausted and errUnterminatedSequence if it is
+// exhausted part way through a coordinate.
+func (d *Decoder) DecodeCoord() ([]float64, error) {
+	if err := d.c.validate(); err != nil {
+		return nil, err
+	}
+	if err := d.c.readCoordInts(d.r, d.last); err != nil {
+		return nil, err
+	}
+	coord := make([]float64, d.c.Dim)
+	for i, x := range d.last {
+		coord[i] = d.c.toFloat(i, x)
+	}
+	return coord, nil
+}
+
+// readUint reads a single unsigned integer encoded with c's chunk bits from r.
+// It returns io.EOF if r is exhausted before the first byte and
+// errUnterminatedSequence if r is exhausted part way through the integer.
+func (c Codec) readUint(r io.ByteReader) (uint, error) {
+	k, limit := c.chunkBits(), c.terminatorLimit()
+	var u, shift uint
+	for {
+		b, err := r.ReadByte()
+		switch {
+		case err == io.EOF && shift == 0:
+			return 0, io.EOF
+		case err == io.EOF:
+			return 0, errUnterminatedSequence
+		case err != nil:
+			return 0, err
+		case 63 <= b && b < limit:
+			v := uint(b) - 63
+			if v<<shift>>shift != v {
+				return 0, errOverflow
+			}
+			u += v << shift
+			return u, nil
+		case limit <= b && b < 2*limit-63:
+			v := uint(b - limit)
+			if shift >= bits.UintSize || v<<shift>>shift != v {
+				return 0, errOverflow
+			}
+			u += v << shift
+			shift += k
+		default:
+			return 0, errInvalidByte
+		}
+	}
+}
+
+// readInt reads a single signed integer encoded with c's chunk bits from r,
+// with the same error semantics as readUint.
+func (c Codec) readInt(r io.ByteReader) (int, error) {
+	u, err := c.readUint(r)
+	if err != nil {
+		return 0, err
+	}
+	if u&1 == 0 {
+		return int(u >> 1), nil
+	}
+	return ^int(u >> 1), nil
+}
+
+// readCoordInts reads the next c.Dim integers from r and adds them to last. It
+// returns io.EOF if r is exhausted before the first integer and
+// errUnterminatedSequence if r is exhausted part way through the coordinate.
+func (c Codec) readCoordInts(r io.ByteReader, last []int) error {
+	for j := range last {
+		k, err := c.readInt(r)
+		switch {
+		case err == io.EOF && j == 0:
+			return io.EOF
+		case err == io.EOF:
+			return errUnterminatedSequence
+		case err != nil:
+			return err
+		}
+		last[j] += k
+	}
+	return nil
+}
+
+// rescaler returns a function that converts integers scaled by fromScale to
+// integers scaled by toScale. If the ratio of the scales is a power of ten then
+// the conversion is exact integer arithmetic.
+func rescaler(fromScale, toScale float64) func(int) int {
+	fromPrecision, fromOK := scalePrecision(fromScale)
+	toPrecision, toOK := scalePrecision(toScale)
+	switch {
+	case fromOK && toOK && toPrecision >= fromPrecision:
+		mul := int(math.Pow10(toPrecision - fromPrecision))
+		return func(i int) int {
+			return i * mul
+		}
+	case fromOK && toOK:
+		div := int(math.Pow10(fromPrecision - toPrecision))
+		return func(i int) int {
+			if i < 0 {
+				return -((-i + div/2) / div)
+			}
+			return (i + div/2) / div
+		}
+	default:
+		return func(i int) int {
+			return round(toScale * float64(i) / fromScale)
+		}
+	}
+}
+
+// TranscodeStream reads a polyline encoded with from from r and writes it
+// re-encoded with to to w, one coordinate at a time. from and to must have the
+// same dimensionality. It returns any error from reading, decoding, or
+// writing.
+func TranscodeStream(w io.Writer, r io.Reader, from, to Codec) error {
+	if from.Dim != to.Dim {
+		return errDimensionalMismatch
+	}
+	if err := from.validate(); err != nil {
+		return err
+	}
+	if err := to.validate(); err != nil {
+		return err
+	}
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	rescale := make([]func(int) int, from.Dim)
+	for j := range rescale {
+		rescale[j] = rescaler(from.scale(j), to.scale(j))
+	}
+	fromLast := make([]int, from.Dim)
+	toLast := make([]int, to.Dim)
+	var buf []byte
+	for {
+		switch err := from.readCoordInts(br, fromLast); {
+		case err == io.EOF:
+			return bw.Flush()
+		case err != nil:
+			return err
+		}
+		buf = buf[:0]
+		for j, i := range fromLast {
+			k := rescale[j](i)
+			buf = to.EncodeInt(buf, k-toLast[j])
+			toLast[j] = k
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package polyline
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+type errorWriter struct{}
+
+var errTestWrite = errors.New("write error")
+
+func (errorWriter) Write(p []byte) (int, error) {
+	return 0, errTestWrite
+}
+
+// A failOnceWriter writes at most n bytes and returns errTestWrite on its first
+// call, and writes normally on subsequent calls.
+type failOnceWriter struct {
+	bytes.Buffer
+	n      int
+	failed bool
+}
+
+func (w *failOnceWriter) Write(p []byte) (int, error) {
+	if w.failed {
+		return w.Buffer.Write(p)
+	}
+	w.failed = true
+	if len(p) > w.n {
+		p = p[:w.n]
+	}
+	n, _ := w.Buffer.Write(p)
+	return n, errTestWrite
+}
+
+func TestBufferedEncoder(t *testing.T) {
+	for _, tc := range []struct {
+		threshold int
+		writes    int
+	}{
+		{threshold: 0, writes: 1},
+		{threshold: 1, writes: 3},
+		{threshold: 12, writes: 2},
+		{threshold: 1024, writes: 1},
+	} {
+		w := &countingWriter{}
+		e := NewBufferedEncoder(w, defaultCodec, tc.threshold)
+		for _, coord := range [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}} {
+			assert.NoError(t, e.EncodeCoord(coord))
+		}
+		assert.NoError(t, e.Close())
+		assert.Equal(t, "_p~iF~ps|U_ulLnnqC_mqNvxq`@", w.String())
+		assert.Equal(t, tc.writes, w.writes)
+	}
+}
+
+func TestBufferedEncoderErrors(t *testing.T) {
+	e := NewBufferedEncoder(&bytes.Buffer{}, defaultCodec, 0)
+	assert.Equal(t, errDimensionalMismatch, e.EncodeCoord([]float64{1}))
+	e = NewBufferedEncoder(errorWriter{}, defaultCodec, 1)
+	assert.Equal(t, errTestWrite, e.EncodeCoord([]float64{1, 2}))
+}
+
+func TestBufferedEncoderFlushRetry(t *testing.T) {
+	for _, n := range []int{0, 5} {
+		w := &failOnceWriter{n: n}
+		e := NewBufferedEncoder(w, defaultCodec, 1024)
+		for _, coord := range [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}} {
+			assert.NoError(t, e.EncodeCoord(coord))
+		}
+		assert.Equal(t, errTestWrite, e.Flush())
+		assert.NoError(t, e.Close())
+		assert.Equal(t, "_p~iF~ps|U_ulLnnqC_mqNvxq`@", w.String())
+	}
+}
+
+func TestTranscodeStream(t *testing.T) {
+	for _, tc := range []struct {
+		from Codec
+		to   Codec
+		s    string
+		want string
+	}{
+		{
+			from: Codec{Dim: 2, Scale: 1e5},
+			to:   Codec{Dim: 2, Scale: 1e6},
+			s:    "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			want: "_izlhA~rlgdF_{geC~ywl@_kwzCn`{nI",
+		},
+		{
+			from: Codec{Dim: 2, Scale: 1e6},
+			to:   Codec{Dim: 2, Scale: 1e5},
+			s:    "_izlhA~rlgdF_{geC~ywl@_kwzCn`{nI",
+			want: "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			from: Codec{Dim: 2, Scale: 1e6},
+			to:   Codec{Dim: 2, Scale: 1e5},
+			s:    string(Codec{Dim: 2, Scale: 1e6}.EncodeCoords(nil, [][]float64{{1.000005, -1.000005}, {1.000004, -1.000004}})),
+			want: string(EncodeCoords([][]float64{{1.00001, -1.00001}, {1, -1}})),
+		},
+		{
+			from: Codec{Dim: 2, Scale: 1e5},
+			to:   Codec{Dim: 2, Scale: 2e5},
+			s:    "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			want: string(Codec{Dim: 2, Scale: 2e5}.EncodeCoords(nil, [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}})),
+		},
+		{
+			from: Codec{Dim: 2, Scale: 1e5},
+			to:   Codec{Dim: 2, Scale: 1e5, ChunkBits: 3},
+			s:    "_p~iF~ps|U_ulLnnqC",
+			want: "GKGNNILBNNGHLLJJ@GGJJJL@NLNKKC",
+		},
+		{
+			from: Codec{Dim: 2, Scale: 1e5, ChunkBits: 3},
+			to:   Codec{Dim: 2, Scale: 1e5},
+			s:    "GKGNNILBNNGHLLJJ@GGJJJL@NLNKKC",
+			want: "_p~iF~ps|U_ulLnnqC",
+		},
+		{
+			from: Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{0, 0, 1e2}},
+			to:   Codec{Dim: 3, Scale: 1e6, Scales: [MaxScales]float64{0, 0, 1e2}},
+			s:    string(Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{0, 0, 1e2}}.EncodeCoords(nil, [][]float64{{38.5, -120.2, 100.25}, {40.7, -120.95, 99.5}})),
+			want: string(Codec{Dim: 3, Scale: 1e6, Scales: [MaxScales]float64{0, 0, 1e2}}.EncodeCoords(nil, [][]float64{{38.5, -120.2, 100.25}, {40.7, -120.95, 99.5}})),
+		},
+		{
+			from: Codec{Dim: 3, Scale: 1e5},
+			to:   Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{0, 0, 1e2}},
+			s:    string(Codec{Dim: 3, Scale: 1e5}.EncodeCoords(nil, [][]float64{{38.5, -120.2, 100.25}})),
+			want: string(Codec{Dim: 3, Scale: 1e5, Scales: [MaxScales]float64{0, 0, 1e2}}.EncodeCoords(nil, [][]float64{{38.5, -120.2, 100.25}})),
+		},
+		{
+			from: Codec{Dim: 2, Scale: 1e5},
+			to:   Codec{Dim: 2, Scale: 1e6},
+			s:    "",
+			want: "",
+		},
+	} {
+		var w bytes.Buffer
+		assert.NoError(t, TranscodeStream(&w, bytes.NewBufferString(tc.s), tc.from, tc.to))
+		assert.Equal(t, tc.want, w.String())
+	}
+}
+
+func TestTranscodeStreamErrors(t *testing.T) {
+	for _, tc := range []struct {
+		w    io.Writer
+		from Codec
+		to   Codec
+		s    string
+		err  error
+	}{
+		{w: &bytes.Buffer{}, from: Codec{Dim: 2, Scale: 1e5}, to: Codec{Dim: 3, Scale: 1e5}, err: errDimensionalMismatch},
+		{w: &bytes.Buffer{}, from: defaultCodec, to: defaultCodec, s: "_p~iF", err: errUnterminatedSequence},
+		{w: &bytes.Buffer{}, from: defaultCodec, to: defaultCodec, s: "_p~iF~ps|", err: errUnterminatedSequence},
+		{w: &bytes.Buffer{}, from: defaultCodec, to: defaultCodec, s: "_p~iF>", err: errInvalidByte},
+		{w: errorWriter{}, from: defaultCodec, to: defaultCodec, s: "_p~iF~ps|U", err: errTestWrite},
+	} {
+		assert.Equal(t, tc.err, TranscodeStream(tc.w, bytes.NewBufferString(tc.s), tc.from, tc.to))
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	for _, tc := range []struct {
+		c  Codec
+		cs [][]float64
+	}{
+		{
+			c:  defaultCodec,
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+		},
+		{
+			c:  Codec{Dim: 3, Scale: 1, ChunkBits: 4},
+			cs: [][]float64{{1, 2, 3}, {-100, 200, -300}},
+		},
+	} {
+		w := &countingWriter{}
+		bw := bufio.NewWriter(w)
+		e := NewEncoder(bw, tc.c)
+		for _, coord := range tc.cs {
+			assert.NoError(t, e.EncodeCoord(coord))
+		}
+		assert.Equal(t, 0, w.writes)
+		assert.NoError(t, e.Flush())
+		assert.Equal(t, 1, w.writes)
+		assert.Equal(t, string(tc.c.EncodeCoords(nil, tc.cs)), w.String())
+
+		w = &countingWriter{}
+		e = NewEncoder(w, tc.c)
+		for _, coord := range tc.cs {
+			assert.NoError(t, e.EncodeCoord(coord))
+		}
+		assert.NoError(t, e.Flush())
+		assert.Equal(t, len(tc.cs), w.writes)
+		assert.Equal(t, string(tc.c.EncodeCoords(nil, tc.cs)), w.String())
+	}
+}
+
+func TestEncoderErrors(t *testing.T) {
+	e := NewEncoder(&bytes.Buffer{}, defaultCodec)
+	assert.Equal(t, errDimensionalMismatch, e.EncodeCoord([]float64{1, 2, 3}))
+	e = NewEncoder(errorWriter{}, defaultCodec)
+	assert.Equal(t, errTestWrite, e.EncodeCoord([]float64{1, 2}))
+}
+
+func TestSimplifyingEncoder(t *testing.T) {
+	for _, tc := range []struct {
+		coords  [][]float64
+		epsilon float64
+		want    [][]float64
+	}{
+		{
+			coords:  nil,
+			epsilon: 0.1,
+			want:    nil,
+		},
+		{
+			coords:  [][]float64{{38.5, -120.2}},
+			epsilon: 0.1,
+			want:    [][]float64{{38.5, -120.2}},
+		},
+		{
+			coords:  [][]float64{{38.5, -120.2}, {40.7, -120.95}},
+			epsilon: 0.1,
+			want:    [][]float64{{38.5, -120.2}, {40.7, -120.95}},
+		},
+		{
+			coords:  [][]float64{{0, 0}, {0, 1}, {0.01, 2}, {0, 3}, {1, 3}, {2, 3}},
+			epsilon: 0.1,
+			want:    [][]float64{{0, 0}, {0, 3}, {2, 3}},
+		},
+		{
+			coords:  [][]float64{{0, 0}, {0, 1}, {0.01, 2}, {0, 3}, {1, 3}, {2, 3}},
+			epsilon: 0.001,
+			want:    [][]float64{{0, 0}, {0, 1}, {0.01, 2}, {0, 3}, {2, 3}},
+		},
+	} {
+		e := NewSimplifyingEncoder(defaultCodec, tc.epsilon)
+		for _, coord := range tc.coords {
+			assert.NoError(t, e.WriteCoord(coord))
+		}
+		assert.Equal(t, string(EncodeCoords(tc.want)), string(e.Close()))
+	}
+}
+
+func TestSimplifyingEncoderErrors(t *testing.T) {
+	e := NewSimplifyingEncoder(defaultCodec, 0)
+	assert.Equal(t, errDimensionalMismatch, e.WriteCoord([]float64{1, 2, 3}))
+	e = NewSimplifyingEncoder(Codec{Dim: 1, Scale: 1}, 0)
+	assert.Equal(t, errDimensionalMismatch, e.WriteCoord([]float64{1}))
+}
+
+func TestDecoder(t *testing.T) {
+	for _, tc := range []struct {
+		c  Codec
+		cs [][]float64
+		s  string
+	}{
+		{
+			c:  defaultCodec,
+			cs: [][]float64{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}},
+			s:  "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			c:  Codec{Dim: 1, Scale: 1, ChunkBits: 3},
+			cs: [][]float64{{1}, {-100}, {200}},
+		},
+	} {
+		s := tc.s
+		if s == "" {
+			s = string(tc.c.EncodeCoords(nil, tc.cs))
+		}
+		for _, r := range []io.Reader{
+			strings.NewReader(s),
+			iotest.OneByteReader(strings.NewReader(s)),
+			iotest.HalfReader(strings.NewReader(s)),
+		} {
+			d := NewDecoder(r, tc.c)
+			var got [][]float64
+			for {
+				coord, err := d.DecodeCoord()
+				if err == io.EOF {
+					break
+				}
+				assert.NoError(t, err)
+				if err != nil {
+					break
+				}
+				got = append(got, coord)
+			}
+			assert.Equal(t, tc.cs, got)
+		}
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	for _, tc := range []struct {
+		s   string
+		err error
+	}{
+		{s: "_p~iF~ps|U_", err: errUnterminatedSequence},
+		{s: "_p~iF~ps|U_ulL", err: errUnterminatedSequence},
+		{s: "_p~iF~ps|U!", err: errInvalidByte},
+	} {
+		d := NewDecoder(iotest.OneByteReader(strings.NewReader(tc.s)), defaultCodec)
+		coord, err := d.DecodeCoord()
+		assert.NoError(t, err)
+		assert.Equal(t, []float64{38.5, -120.2}, coord)
+		_, err = d.DecodeCoord()
+		assert.Equal(t, tc.err, err)
+	}
+	d := NewDecoder(iotest.OneByteReader(iotest.TimeoutReader(strings.NewReader("_p~iF~ps|U"))), defaultCodec)
+	_, err := d.DecodeCoord()
+	assert.Equal(t, iotest.ErrTimeout, err)
+}
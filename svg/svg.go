@@ -0,0 +1,111 @@
+// Package svg converts SVG path data to polylines.
+package svg
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/twpayne/go-polyline"
+)
+
+var (
+	errInvalidPath        = errors.New("invalid path")
+	errMultipleSubpaths   = errors.New("multiple subpaths")
+	errUnsupportedCommand = errors.New("unsupported command")
+)
+
+// EncodeFromSVGPath parses the SVG path data d and returns the encoding of its
+// coordinates using the default codec, and any error. d may contain only
+// absolute moveto (M) and lineto (L) commands, and must consist of a single
+// subpath, that is a single moveto at the start. By convention, each x is a
+// longitude and each y a latitude.
+func EncodeFromSVGPath(d string) ([]byte, error) {
+	var coords [][]float64
+	var command byte
+	var xy []float64
+	for i := 0; ; {
+		for i < len(d) && isSeparator(d[i]) {
+			i++
+		}
+		if i == len(d) {
+			break
+		}
+		switch b := d[i]; {
+		case b == 'M':
+			if command != 0 {
+				return nil, errMultipleSubpaths
+			}
+			command = b
+			i++
+		case b == 'L':
+			if command == 0 || len(xy) != 0 {
+				return nil, errInvalidPath
+			}
+			command = b
+			i++
+		case 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z':
+			return nil, errUnsupportedCommand
+		default:
+			if command == 0 {
+				return nil, errInvalidPath
+			}
+			j := scanNumber(d, i)
+			x, err := strconv.ParseFloat(d[i:j], 64)
+			if err != nil {
+				return nil, errInvalidPath
+			}
+			i = j
+			xy = append(xy, x)
+			if len(xy) == 2 {
+				coords = append(coords, []float64{xy[1], xy[0]})
+				xy = xy[:0]
+			}
+		}
+	}
+	if len(coords) == 0 || len(xy) != 0 {
+		return nil, errInvalidPath
+	}
+	return polyline.EncodeCoords(coords), nil
+}
+
+// isSeparator returns whether b separates tokens in SVG path data.
+func isSeparator(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\r', '\f', ',':
+		return true
+	default:
+		return false
+	}
+}
+
+// scanNumber returns the index of the end of the number starting at index i of
+// d. Numbers may be adjacent, as in "1-2" or "1.5.5", so scanning stops at the
+// first byte that cannot continue the number.
+func scanNumber(d string, i int) int {
+	if i < len(d) && (d[i] == '+' || d[i] == '-') {
+		i++
+	}
+	i = scanDigits(d, i)
+	if i < len(d) && d[i] == '.' {
+		i = scanDigits(d, i+1)
+	}
+	if i < len(d) && (d[i] == 'e' || d[i] == 'E') {
+		j := i + 1
+		if j < len(d) && (d[j] == '+' || d[j] == '-') {
+			j++
+		}
+		if k := scanDigits(d, j); k > j {
+			i = k
+		}
+	}
+	return i
+}
+
+// scanDigits returns the index of the first non-digit at or after index i of
+// d.
+func scanDigits(d string, i int) int {
+	for i < len(d) && '0' <= d[i] && d[i] <= '9' {
+		i++
+	}
+	return i
+}
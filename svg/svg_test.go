@@ -0,0 +1,62 @@
+package svg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeFromSVGPath(t *testing.T) {
+	for _, tc := range []struct {
+		d    string
+		want string
+	}{
+		{
+			d:    "M -120.2 38.5 L -120.95 40.7 L -126.453 43.252",
+			want: "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			d:    "M-120.2,38.5L-120.95,40.7-126.453,43.252",
+			want: "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			d:    "M -120.2 38.5 -120.95 40.7",
+			want: "_p~iF~ps|U_ulLnnqC",
+		},
+		{
+			d:    "M-1.202e2 385e-1",
+			want: "_p~iF~ps|U",
+		},
+		{
+			d:    "M0 .5.5 0",
+			want: "_t`B?~s`B_t`B",
+		},
+	} {
+		got, err := EncodeFromSVGPath(tc.d)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, string(got))
+	}
+}
+
+func TestEncodeFromSVGPathErrors(t *testing.T) {
+	for _, tc := range []struct {
+		d   string
+		err error
+	}{
+		{d: "", err: errInvalidPath},
+		{d: "M", err: errInvalidPath},
+		{d: "0 0", err: errInvalidPath},
+		{d: "L 0 0", err: errInvalidPath},
+		{d: "M 0 0 1", err: errInvalidPath},
+		{d: "M 0 0 1 L 2 3", err: errInvalidPath},
+		{d: "M 0 0 L #", err: errInvalidPath},
+		{d: "M 0 0 L .", err: errInvalidPath},
+		{d: "M 0 0 L 1 1 M 2 2", err: errMultipleSubpaths},
+		{d: "M 0 0 l 1 1", err: errUnsupportedCommand},
+		{d: "M 0 0 C 1 1 2 2 3 3", err: errUnsupportedCommand},
+		{d: "M 0 0 Z", err: errUnsupportedCommand},
+	} {
+		_, err := EncodeFromSVGPath(tc.d)
+		assert.Equal(t, tc.err, err, tc.d)
+	}
+}
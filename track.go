@@ -0,0 +1,102 @@
+package polyline
+
+// A Point3D is a three-dimensional point. Alt is in whatever unit the caller
+// chooses, typically meters, and its resolution is determined by the scale
+// passed to EncodeTrack3D and DecodeTrack3D independently of Lat and Lng.
+type Point3D struct {
+	Lat float64
+	Lng float64
+	Alt float64
+}
+
+// EncodeTrack3D appends the encoding of points to buf and returns the new buf.
+// Lat and Lng are scaled by 1e5, as with the default codec, and Alt is scaled
+// by altScale.
+func EncodeTrack3D(buf []byte, points []Point3D, altScale float64) []byte {
+	var last [3]int
+	for _, p := range points {
+		for i, ex := range [3]int{
+			round(defaultCodec.Scale * p.Lat),
+			round(defaultCodec.Scale * p.Lng),
+			round(altScale * p.Alt),
+		} {
+			buf = EncodeInt(buf, ex-last[i])
+			last[i] = ex
+		}
+	}
+	return buf
+}
+
+// DecodeTrack3D decodes an array of points encoded with EncodeTrack3D from
+// buf. altScale must be the same as that used for encoding. It returns the
+// points and any error.
+func DecodeTrack3D(buf []byte, altScale float64) ([]Point3D, error) {
+	var points []Point3D
+	var last [3]int
+	for {
+		var err error
+		if buf, err = defaultCodec.decodeInts(buf, last[:]); err != nil {
+			return nil, err
+		}
+		points = append(points, Point3D{
+			Lat: float64(last[0]) / defaultCodec.Scale,
+			Lng: float64(last[1]) / defaultCodec.Scale,
+			Alt: float64(last[2]) / altScale,
+		})
+		if len(buf) == 0 {
+			return points, nil
+		}
+	}
+}
+
+// EncodeTimedTrack appends the encoding of coords, which must be
+// two-dimensional, and their timestamps unixMillis to buf. Lat and Lng are
+// scaled by 1e5, as with the default codec, and timestamps are encoded as
+// exact integer deltas. It returns the new buf and any error.
+func EncodeTimedTrack(buf []byte, coords [][]float64, unixMillis []int64) ([]byte, error) {
+	if len(coords) != len(unixMillis) {
+		return nil, errLengthMismatch
+	}
+	var lastLat, lastLng int
+	var lastTime int64
+	for i, coord := range coords {
+		if len(coord) != 2 {
+			return nil, errDimensionalMismatch
+		}
+		lat, lng := round(defaultCodec.Scale*coord[0]), round(defaultCodec.Scale*coord[1])
+		buf = EncodeInt(buf, lat-lastLat)
+		buf = EncodeInt(buf, lng-lastLng)
+		buf = EncodeInt64(buf, unixMillis[i]-lastTime)
+		lastLat, lastLng, lastTime = lat, lng, unixMillis[i]
+	}
+	return buf, nil
+}
+
+// DecodeTimedTrack decodes coordinates and timestamps encoded with
+// EncodeTimedTrack from buf. It returns the coordinates, the timestamps in
+// milliseconds since the Unix epoch, and any error.
+func DecodeTimedTrack(buf []byte) ([][]float64, []int64, error) {
+	var coords [][]float64
+	var unixMillis []int64
+	var last [2]int
+	var lastTime int64
+	for {
+		var err error
+		var dTime int64
+		if buf, err = defaultCodec.decodeInts(buf, last[:]); err != nil {
+			return nil, nil, err
+		}
+		if dTime, buf, err = DecodeInt64(buf); err != nil {
+			return nil, nil, err
+		}
+		lastTime += dTime
+		coords = append(coords, []float64{
+			float64(last[0]) / defaultCodec.Scale,
+			float64(last[1]) / defaultCodec.Scale,
+		})
+		unixMillis = append(unixMillis, lastTime)
+		if len(buf) == 0 {
+			return coords, unixMillis, nil
+		}
+	}
+}
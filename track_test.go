@@ -0,0 +1,79 @@
+package polyline
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrack3D(t *testing.T) {
+	for _, tc := range []struct {
+		points   []Point3D
+		altScale float64
+		s        string
+	}{
+		{
+			points:   []Point3D{{Lat: 38.5, Lng: -120.2, Alt: 100}, {Lat: 40.7, Lng: -120.95, Alt: 99.5}},
+			altScale: 10,
+			s:        "_p~iF~ps|Uo}@_ulLnnqCH",
+		},
+		{
+			points:   []Point3D{{Lat: 38.5, Lng: -120.2, Alt: 328.084}},
+			altScale: 1000,
+			s:        "_p~iF~ps|Ugx_S",
+		},
+	} {
+		got := EncodeTrack3D(nil, tc.points, tc.altScale)
+		assert.Equal(t, tc.s, string(got))
+		gotPoints, err := DecodeTrack3D(got, tc.altScale)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.points, gotPoints)
+	}
+}
+
+func TestDecodeTrack3DErrors(t *testing.T) {
+	for _, s := range []string{"", "_p~iF~ps|U"} {
+		_, err := DecodeTrack3D([]byte(s), 1)
+		assert.Equal(t, errUnterminatedSequence, err)
+	}
+}
+
+func TestTimedTrack(t *testing.T) {
+	for _, tc := range []struct {
+		coords     [][]float64
+		unixMillis []int64
+		s          string
+	}{
+		{
+			coords:     [][]float64{{38.5, -120.2}, {40.7, -120.95}},
+			unixMillis: []int64{1700000000123, 1700000001456},
+			s:          "_p~iF~ps|Uufst{n}aB_ulLnnqCirA",
+		},
+		{
+			coords:     [][]float64{{0, 0}, {0, 0}, {0, 0}},
+			unixMillis: []int64{-1, math.MaxInt64, math.MinInt64},
+		},
+	} {
+		got, err := EncodeTimedTrack(nil, tc.coords, tc.unixMillis)
+		assert.NoError(t, err)
+		if tc.s != "" {
+			assert.Equal(t, tc.s, string(got))
+		}
+		gotCoords, gotUnixMillis, err := DecodeTimedTrack(got)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.coords, gotCoords)
+		assert.Equal(t, tc.unixMillis, gotUnixMillis)
+	}
+}
+
+func TestTimedTrackErrors(t *testing.T) {
+	_, err := EncodeTimedTrack(nil, [][]float64{{0, 0}}, nil)
+	assert.Equal(t, errLengthMismatch, err)
+	_, err = EncodeTimedTrack(nil, [][]float64{{0, 0, 0}}, []int64{0})
+	assert.Equal(t, errDimensionalMismatch, err)
+	for _, s := range []string{"", "_p~iF~ps|U"} {
+		_, _, err := DecodeTimedTrack([]byte(s))
+		assert.Equal(t, errUnterminatedSequence, err)
+	}
+}